@@ -16,6 +16,10 @@ import type {
   ReorderFooterLinksRequest,
   NewsletterAutomationSettings,
   UpdateNewsletterAutomationRequest,
+  NewsletterStats,
+  EmailSuppression,
+  SuppressionReason,
+  SubscriberImportReport,
 } from "./types";
 
 // Runtime API base resolution to avoid SSR picking the wrong value.
@@ -255,10 +259,10 @@ class ApiClient {
     );
   }
 
-  async unsubscribeFromNewsletter(email: string) {
+  async unsubscribeFromNewsletter(token: string) {
     return this.request<{ message: string }>("/newsletter/unsubscribe", {
       method: "POST",
-      body: JSON.stringify({ email }),
+      body: JSON.stringify({ token }),
     });
   }
 
@@ -269,9 +273,7 @@ class ApiClient {
   }
 
   async getNewsletterStats() {
-    return this.request<{ active_subscribers: number }>(
-      "/admin/newsletter/stats",
-    );
+    return this.request<NewsletterStats>("/admin/newsletter/stats");
   }
 
   async getNewsletterSubscribers() {
@@ -340,6 +342,89 @@ class ApiClient {
     );
   }
 
+  async exportNewsletterSubscribers(): Promise<Blob> {
+    const url = `${getApiBase()}/admin/newsletter/subscribers/export`;
+
+    const headers: Record<string, string> = {};
+    if (this.token) {
+      headers.Authorization = `Bearer ${this.token}`;
+    }
+
+    const response = await fetch(url, { headers });
+
+    if (!response.ok) {
+      const errorData = await response
+        .json()
+        .catch(() => ({ error: "Export failed" }));
+      throw new Error(errorData.error || `HTTP ${response.status}`);
+    }
+
+    return await response.blob();
+  }
+
+  async importNewsletterSubscribers(
+    file: File,
+    dryRun: boolean,
+  ): Promise<SubscriberImportReport> {
+    const formData = new FormData();
+    formData.append("file", file);
+
+    const url = `${getApiBase()}/admin/newsletter/subscribers/import?dry_run=${dryRun}`;
+
+    const headers: Record<string, string> = {};
+    if (this.token) {
+      headers.Authorization = `Bearer ${this.token}`;
+    }
+
+    const response = await fetch(url, {
+      method: "POST",
+      headers,
+      body: formData,
+    });
+
+    if (!response.ok) {
+      const errorData = await response
+        .json()
+        .catch(() => ({ error: "Import failed" }));
+      throw new Error(errorData.error || `HTTP ${response.status}`);
+    }
+
+    return await response.json();
+  }
+
+  async getEmailSuppressions(
+    page: number = 1,
+    limit: number = 20,
+    reason: SuppressionReason | "" = "",
+    search: string = "",
+  ) {
+    return this.request<{
+      suppressions: EmailSuppression[];
+      total: number;
+      page: number;
+      limit: number;
+      total_pages: number;
+    }>(
+      `/admin/newsletter/suppressions?page=${page}&limit=${limit}&reason=${reason}&search=${encodeURIComponent(search)}`,
+    );
+  }
+
+  async createEmailSuppression(email: string, detail: string = "") {
+    return this.request<EmailSuppression>("/admin/newsletter/suppressions", {
+      method: "POST",
+      body: JSON.stringify({ email, detail }),
+    });
+  }
+
+  async deleteEmailSuppression(id: number) {
+    return this.request<{ message: string }>(
+      `/admin/newsletter/suppressions/${id}`,
+      {
+        method: "DELETE",
+      },
+    );
+  }
+
   // Event publishing endpoints
   async getEventPublishStatus(eventId: number) {
     return this.request<{
@@ -393,6 +478,7 @@ class ApiClient {
   ) {
     return this.request<{
       message: string;
+      campaign_id: number;
       subscribers_sent: number;
       total_subscribers: number;
     }>(`/admin/events/${eventId}/newsletter/send`, {
@@ -52,6 +52,7 @@ export interface Event {
   date: string;
   votes: number;
   content: string; // Markdown content
+  content_html?: string; // Content rendered and sanitized by the server
   created_at: string;
   updated_at: string;
   is_public: boolean; // Controls if event appears on public page
@@ -130,8 +131,17 @@ export interface UpdateSettingsRequest {
 }
 
 // Mail settings types
+export type MailProvider =
+  | "smtp"
+  | "postmark"
+  | "sendgrid"
+  | "mailgun"
+  | "ses"
+  | "file";
+
 export interface MailSettings {
   id: number;
+  provider: MailProvider;
   smtp_host: string;
   smtp_port: number;
   smtp_username: string;
@@ -139,11 +149,21 @@ export interface MailSettings {
   smtp_encryption: string;
   from_email: string;
   from_name: string;
+  api_key: string;
+  api_secret: string;
+  api_domain: string;
+  api_region: string;
+  api_endpoint: string;
+  file_path: string;
+  tracking_enabled: boolean;
+  bounce_webhook_secret: string;
+  bounce_webhook_url: string;
   created_at: string;
   updated_at: string;
 }
 
 export interface UpdateMailSettingsRequest {
+  provider?: MailProvider;
   smtp_host?: string;
   smtp_port?: number;
   smtp_username?: string;
@@ -151,6 +171,14 @@ export interface UpdateMailSettingsRequest {
   smtp_encryption?: string;
   from_email?: string;
   from_name?: string;
+  api_key?: string;
+  api_secret?: string;
+  api_domain?: string;
+  api_region?: string;
+  api_endpoint?: string;
+  file_path?: string;
+  tracking_enabled?: boolean;
+  rotate_bounce_webhook_secret?: boolean;
 }
 
 // Footer Link types
@@ -197,6 +225,64 @@ export interface NewsletterAutomationSettings {
   updated_at?: string;
 }
 
+// Open and click stats of tracked newsletter campaigns
+export interface CampaignEngagement {
+  campaign_id: number;
+  event_id: number;
+  subject: string;
+  sent_at: string | null;
+  recipients: number;
+  opens: number;
+  clicks: number;
+  total_opens: number;
+  total_clicks: number;
+  open_rate: number;
+  click_rate: number;
+}
+
+export interface EventEngagement {
+  event_id: number;
+  campaigns: number;
+  recipients: number;
+  opens: number;
+  clicks: number;
+  open_rate: number;
+  click_rate: number;
+}
+
+export interface NewsletterStats {
+  active_subscribers: number;
+  tracking_enabled: boolean;
+  campaigns: CampaignEngagement[];
+  events: EventEngagement[];
+}
+
+// Result of a subscriber import, or of a dry run
+export interface SubscriberImportReport {
+  dry_run: boolean;
+  total: number;
+  created: number;
+  existing: number;
+  unsubscribed: number;
+  duplicates: number;
+  suppressed: number;
+  invalid: number;
+  errors: { line: number; email: string; error: string }[];
+}
+
+// Addresses that no email is sent to after a bounce or complaint
+export type SuppressionReason = "hard_bounce" | "complaint" | "manual";
+
+export interface EmailSuppression {
+  id: number;
+  email: string;
+  reason: SuppressionReason;
+  source: string;
+  detail: string;
+  created_at: string;
+  updated_at: string;
+}
+
 export interface UpdateNewsletterAutomationRequest {
   enabled?: boolean;
   trigger_statuses?: EventStatus[];
@@ -2,14 +2,16 @@ package constants
 
 // EmailTemplateTypes defines the available email template types
 const (
-	TemplateTypeEvent   = "event"
-	TemplateTypeWelcome = "welcome"
+	TemplateTypeEvent        = "event"
+	TemplateTypeWelcome      = "welcome"
+	TemplateTypeConfirmation = "confirmation"
 )
 
 // Email template subjects
 const (
-	SubjectEvent   = "{{status}}: {{event_name}} - {{project_name}}"
-	SubjectWelcome = "Welcome to {{project_name}}!"
+	SubjectEvent        = "{{status}}: {{event_name}} - {{project_name}}"
+	SubjectWelcome      = "Welcome to {{project_name}}!"
+	SubjectConfirmation = "Confirm your subscription to {{project_name}}"
 )
 
 // Email template content
@@ -42,7 +44,7 @@ const (
     <div style="text-align: center; font-size: 12px; color: #666;">
         <p style="margin: 5px 0;">
             <a href="{{project_url}}" style="color: #2563eb; text-decoration: none;">{{project_name}}</a>
-            <br><a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">Unsubscribe</a>
+            <br><a href="{{preferences_url}}" style="color: #2563eb; text-decoration: none;">Manage preferences</a> · <a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">Unsubscribe</a>
         </p>
     </div>
 </body>`
@@ -63,7 +65,31 @@ const (
     <div style="text-align: center; font-size: 12px; color: #666;">
         <p style="margin: 5px 0;">
             <a href="{{project_url}}" style="color: #2563eb; text-decoration: none;">{{project_name}}</a>
-            <br><a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">Unsubscribe</a>
+            <br><a href="{{preferences_url}}" style="color: #2563eb; text-decoration: none;">Manage preferences</a> · <a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">Unsubscribe</a>
+        </p>
+    </div>
+</body>`
+
+	TemplateConfirmation = `<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <h1 style="color: #000000; text-align: center; font-size: 28px; font-weight: bold; margin: 20px 0;">Confirm your subscription</h1>
+
+    <div style="padding: 20px; margin-bottom: 20px;">
+        <div style="margin: 15px 0; font-size: 16px; line-height: 1.6;">
+            Someone (hopefully you) asked to receive updates from {{project_name}} at this address. Please confirm your subscription by clicking the button below.
+        </div>
+        <div style="text-align: center; margin-top: 30px;">
+            <a href="{{confirm_url}}" style="background: #3b82f6; color: white; padding: 14px 28px; text-decoration: none; border-radius: 6px; display: inline-block; font-weight: bold; font-size: 16px;">Confirm subscription</a>
+        </div>
+        <div style="margin-top: 30px; font-size: 14px; color: #6b7280;">
+            If you didn't request this, you can ignore this email and you won't be subscribed.
+        </div>
+    </div>
+
+    <hr style="border: none; border-top: 1px solid #eee; margin: 30px 0;">
+
+    <div style="text-align: center; font-size: 12px; color: #666;">
+        <p style="margin: 5px 0;">
+            <a href="{{project_url}}" style="color: #2563eb; text-decoration: none;">{{project_name}}</a>
         </p>
     </div>
 </body>`
@@ -89,6 +115,11 @@ func GetDefaultTemplates() []EmailTemplateData {
 			Subject: SubjectWelcome,
 			Content: TemplateWelcome,
 		},
+		{
+			Type:    TemplateTypeConfirmation,
+			Subject: SubjectConfirmation,
+			Content: TemplateConfirmation,
+		},
 	}
 }
 
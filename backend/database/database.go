@@ -73,6 +73,8 @@ func migrate() error {
 		&models.ProjectSettings{},
 		&models.Vote{},
 		&models.EventReaction{},
+		&models.ReactionDefinition{},
+		&models.IPHashKey{},
 		&models.MailSettings{},
 		&models.NewsletterSubscriber{},
 		&models.NewsletterHistory{},
@@ -80,6 +82,15 @@ func migrate() error {
 		&models.NewsletterAutomationSettings{},
 		&models.StatusCategoryMapping{},
 		&models.ThemeSettingValue{},
+		&models.User{},
+		&models.AuditEntry{},
+		&models.EventRevision{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.EmailCampaign{},
+		&models.EmailJob{},
+		&models.EmailTrackingEvent{},
+		&models.EmailSuppression{},
 	); err != nil {
 		// If AutoMigrate fails on project_settings, it's likely corrupted
 		log.Printf("AutoMigrate failed: %v", err)
@@ -103,6 +114,21 @@ func migrate() error {
 		log.Printf("Warning: Failed to seed status definitions: %v", err)
 	}
 
+	// Seed reaction definitions (defaults + keys already used by events)
+	if err := models.SeedReactionDefinitions(DB); err != nil {
+		log.Printf("Warning: Failed to seed reaction definitions: %v", err)
+	}
+
+	// Keep usernames unique regardless of case among live accounts
+	if err := models.MigrateUsernameIndex(DB); err != nil {
+		log.Printf("Warning: Failed to migrate username index: %v", err)
+	}
+
+	// Create the first owner account from the legacy admin credentials
+	if err := models.SeedInitialOwner(DB); err != nil {
+		log.Printf("Warning: Failed to seed initial owner account: %v", err)
+	}
+
 	// Ensure newsletter automation settings table exists (manual fallback)
 	if err := createNewsletterAutomationTableIfNotExists(DB); err != nil {
 		log.Printf("Warning: Failed to create newsletter automation table: %v", err)
@@ -0,0 +1,297 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Bounce types
+const (
+	BounceHard      = "hard"      // permanent failure, the address should not be mailed again
+	BounceSoft      = "soft"      // temporary failure such as a full mailbox
+	BounceComplaint = "complaint" // the recipient reported the email as spam
+)
+
+// BounceSourceGeneric is the webhook source for payloads in ShipShipShip's own format
+const BounceSourceGeneric = "generic"
+
+// Bounce is a delivery failure or spam complaint reported for one recipient
+type Bounce struct {
+	Email  string `json:"email"`
+	Type   string `json:"type"` // hard, soft or complaint
+	Detail string `json:"detail"`
+}
+
+// IsBounceWebhookSource reports whether bounce webhooks of a source can be parsed
+func IsBounceWebhookSource(source string) bool {
+	switch source {
+	case ProviderPostmark, ProviderSendGrid, ProviderMailgun, ProviderSES, BounceSourceGeneric:
+		return true
+	}
+	return false
+}
+
+// ParseBounceWebhook extracts bounces and complaints from a provider's webhook payload.
+// Deliveries, opens and other events in the payload are ignored.
+func ParseBounceWebhook(source string, body []byte) ([]Bounce, error) {
+	var bounces []Bounce
+	var err error
+	switch source {
+	case ProviderPostmark:
+		bounces, err = parsePostmarkBounce(body)
+	case ProviderSendGrid:
+		bounces, err = parseSendGridEvents(body)
+	case ProviderMailgun:
+		bounces, err = parseMailgunEvent(body)
+	case ProviderSES:
+		bounces, err = parseSESNotification(body)
+	case BounceSourceGeneric:
+		bounces, err = parseGenericBounces(body)
+	default:
+		return nil, fmt.Errorf("unknown bounce source: %s", source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s bounce payload: %w", source, err)
+	}
+
+	// Drop entries without a usable address
+	valid := bounces[:0]
+	for _, bounce := range bounces {
+		bounce.Email = strings.TrimSpace(bounce.Email)
+		if validateAddress(bounce.Email) == nil {
+			valid = append(valid, bounce)
+		}
+	}
+	return valid, nil
+}
+
+// SNSSubscribeURL returns the URL that confirms an Amazon SNS subscription when body is
+// a subscription confirmation, or "" otherwise. Only https URLs on amazonaws.com are returned.
+func SNSSubscribeURL(body []byte) string {
+	var envelope struct {
+		Type         string
+		SubscribeURL string
+	}
+	if json.Unmarshal(body, &envelope) != nil || envelope.Type != "SubscriptionConfirmation" {
+		return ""
+	}
+
+	parsed, err := url.Parse(envelope.SubscribeURL)
+	if err != nil || parsed.Scheme != "https" || !strings.HasSuffix(strings.ToLower(parsed.Hostname()), ".amazonaws.com") {
+		return ""
+	}
+	return parsed.String()
+}
+
+// parsePostmarkBounce reads a Postmark bounce or spam complaint webhook
+func parsePostmarkBounce(body []byte) ([]Bounce, error) {
+	var event struct {
+		RecordType  string
+		Type        string
+		Email       string
+		Description string
+		Details     string
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+
+	switch event.RecordType {
+	case "SpamComplaint":
+		return []Bounce{{Email: event.Email, Type: BounceComplaint, Detail: event.Type}}, nil
+	case "Bounce":
+		bounce := Bounce{Email: event.Email, Type: BounceSoft, Detail: joinDetail(event.Description, event.Details)}
+		switch event.Type {
+		case "HardBounce", "BadEmailAddress", "ManuallyDeactivated":
+			bounce.Type = BounceHard
+		case "SpamNotification", "SpamComplaint":
+			bounce.Type = BounceComplaint
+		}
+		return []Bounce{bounce}, nil
+	}
+	return nil, nil
+}
+
+// parseSendGridEvents reads a batch of SendGrid event webhook events
+func parseSendGridEvents(body []byte) ([]Bounce, error) {
+	var events []struct {
+		Email  string `json:"email"`
+		Event  string `json:"event"`
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, err
+	}
+
+	var bounces []Bounce
+	for _, event := range events {
+		switch event.Event {
+		case "bounce":
+			// "blocked" bounces are usually reputation or content problems, not a bad address
+			bounceType := BounceHard
+			if event.Type == "blocked" {
+				bounceType = BounceSoft
+			}
+			bounces = append(bounces, Bounce{Email: event.Email, Type: bounceType, Detail: joinDetail(event.Status, event.Reason)})
+		case "dropped":
+			// SendGrid drops mail to addresses on its own bounce and spam report lists
+			reason := strings.ToLower(event.Reason)
+			if strings.Contains(reason, "bounced") || strings.Contains(reason, "invalid") {
+				bounces = append(bounces, Bounce{Email: event.Email, Type: BounceHard, Detail: event.Reason})
+			} else if strings.Contains(reason, "spam") {
+				bounces = append(bounces, Bounce{Email: event.Email, Type: BounceComplaint, Detail: event.Reason})
+			}
+		case "spamreport":
+			bounces = append(bounces, Bounce{Email: event.Email, Type: BounceComplaint, Detail: "spamreport"})
+		}
+	}
+	return bounces, nil
+}
+
+// parseMailgunEvent reads a Mailgun webhook for failed or complained events
+func parseMailgunEvent(body []byte) ([]Bounce, error) {
+	var payload struct {
+		EventData struct {
+			Event          string `json:"event"`
+			Severity       string `json:"severity"`
+			Reason         string `json:"reason"`
+			Recipient      string `json:"recipient"`
+			DeliveryStatus struct {
+				Code        int    `json:"code"`
+				Message     string `json:"message"`
+				Description string `json:"description"`
+			} `json:"delivery-status"`
+		} `json:"event-data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	event := payload.EventData
+	switch event.Event {
+	case "complained":
+		return []Bounce{{Email: event.Recipient, Type: BounceComplaint, Detail: "complained"}}, nil
+	case "failed":
+		bounceType := BounceSoft
+		if event.Severity == "permanent" {
+			bounceType = BounceHard
+		}
+		detail := joinDetail(event.DeliveryStatus.Message, event.DeliveryStatus.Description)
+		if event.DeliveryStatus.Code != 0 {
+			detail = joinDetail(fmt.Sprint(event.DeliveryStatus.Code), detail)
+		}
+		return []Bounce{{Email: event.Recipient, Type: bounceType, Detail: joinDetail(detail, event.Reason)}}, nil
+	}
+	return nil, nil
+}
+
+// parseSESNotification reads an Amazon SES bounce or complaint notification, either
+// delivered through SNS or posted directly (for example by an EventBridge rule)
+func parseSESNotification(body []byte) ([]Bounce, error) {
+	var envelope struct {
+		Type    string
+		Message string
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Type == "SubscriptionConfirmation" || envelope.Type == "UnsubscribeConfirmation" {
+		return nil, nil
+	}
+	if envelope.Type == "Notification" {
+		body = []byte(envelope.Message)
+	}
+
+	var notification struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"` // used by configuration set event publishing
+		Bounce           struct {
+			BounceType        string `json:"bounceType"`
+			BounceSubType     string `json:"bounceSubType"`
+			BouncedRecipients []struct {
+				EmailAddress   string `json:"emailAddress"`
+				Status         string `json:"status"`
+				DiagnosticCode string `json:"diagnosticCode"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint struct {
+			ComplaintFeedbackType string `json:"complaintFeedbackType"`
+			ComplainedRecipients  []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+		} `json:"complaint"`
+	}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, err
+	}
+
+	kind := notification.NotificationType
+	if kind == "" {
+		kind = notification.EventType
+	}
+
+	var bounces []Bounce
+	switch kind {
+	case "Bounce":
+		bounceType := BounceSoft
+		if notification.Bounce.BounceType == "Permanent" {
+			bounceType = BounceHard
+		}
+		for _, recipient := range notification.Bounce.BouncedRecipients {
+			detail := recipient.DiagnosticCode
+			if detail == "" {
+				detail = joinDetail(recipient.Status, notification.Bounce.BounceSubType)
+			}
+			bounces = append(bounces, Bounce{Email: recipient.EmailAddress, Type: bounceType, Detail: detail})
+		}
+	case "Complaint":
+		for _, recipient := range notification.Complaint.ComplainedRecipients {
+			bounces = append(bounces, Bounce{
+				Email:  recipient.EmailAddress,
+				Type:   BounceComplaint,
+				Detail: notification.Complaint.ComplaintFeedbackType,
+			})
+		}
+	}
+	return bounces, nil
+}
+
+// parseGenericBounces reads one bounce object or an array of them
+func parseGenericBounces(body []byte) ([]Bounce, error) {
+	var bounces []Bounce
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+		if err := json.Unmarshal(body, &bounces); err != nil {
+			return nil, err
+		}
+	} else {
+		var bounce Bounce
+		if err := json.Unmarshal(body, &bounce); err != nil {
+			return nil, err
+		}
+		bounces = []Bounce{bounce}
+	}
+
+	for i, bounce := range bounces {
+		switch bounce.Type {
+		case BounceHard, BounceSoft, BounceComplaint:
+		default:
+			return nil, fmt.Errorf("entry %d: type must be hard, soft or complaint", i)
+		}
+	}
+	return bounces, nil
+}
+
+// joinDetail joins the non-empty parts of a bounce description
+func joinDetail(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, ": ")
+}
@@ -0,0 +1,150 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Maximum size of a bounce message that is parsed; reports are small
+const maxBounceMessageSize = 10 << 20
+
+// ParseBounceMessage extracts bounces from a delivery status notification (RFC 3464)
+// or spam complaints from an abuse feedback report (RFC 5965). Other messages, such as
+// auto-replies, return no bounces.
+func ParseBounceMessage(r io.Reader) ([]Bounce, error) {
+	msg, err := mail.ReadMessage(io.LimitReader(r, maxBounceMessageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || params["boundary"] == "" {
+		return nil, nil
+	}
+
+	var bounces []Bounce
+	var feedback textproto.MIMEHeader
+	var originalHeaders textproto.MIMEHeader
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(decodePart(part))
+		if err != nil {
+			return nil, err
+		}
+
+		switch partType {
+		case "message/delivery-status", "message/global-delivery-status":
+			bounces = append(bounces, parseDeliveryStatus(body)...)
+		case "message/feedback-report":
+			feedback = readHeaderBlocks(body)[0]
+		case "message/rfc822", "text/rfc822-headers", "message/global", "message/global-headers":
+			originalHeaders = readHeaderBlocks(body)[0]
+		}
+	}
+
+	if feedback != nil {
+		recipient := feedback.Get("Original-Rcpt-To")
+		if recipient == "" && originalHeaders != nil {
+			recipient = originalHeaders.Get("To")
+		}
+		if address, err := mail.ParseAddress(recipient); err == nil {
+			detail := feedback.Get("Feedback-Type")
+			bounces = append(bounces, Bounce{Email: address.Address, Type: BounceComplaint, Detail: detail})
+		}
+	}
+
+	valid := bounces[:0]
+	for _, bounce := range bounces {
+		if validateAddress(bounce.Email) == nil {
+			valid = append(valid, bounce)
+		}
+	}
+	return valid, nil
+}
+
+// parseDeliveryStatus reads the per-recipient fields of a delivery-status part.
+// Failed deliveries with a 5.x.x status are hard bounces, other failures and delays soft.
+func parseDeliveryStatus(body []byte) []Bounce {
+	var bounces []Bounce
+	// The per-message fields come first and have no Action, so they are skipped
+	for _, fields := range readHeaderBlocks(body) {
+		action := strings.ToLower(strings.TrimSpace(fields.Get("Action")))
+		if action != "failed" && action != "delayed" {
+			continue
+		}
+
+		recipient := dsnAddress(fields.Get("Final-Recipient"))
+		if recipient == "" {
+			recipient = dsnAddress(fields.Get("Original-Recipient"))
+		}
+		if recipient == "" {
+			continue
+		}
+
+		status := strings.TrimSpace(fields.Get("Status"))
+		bounceType := BounceSoft
+		if action == "failed" && strings.HasPrefix(status, "5") {
+			bounceType = BounceHard
+		}
+		bounces = append(bounces, Bounce{
+			Email:  recipient,
+			Type:   bounceType,
+			Detail: joinDetail(status, fields.Get("Diagnostic-Code")),
+		})
+	}
+	return bounces
+}
+
+// readHeaderBlocks splits a body into header blocks separated by blank lines.
+// The result always holds at least one, possibly empty, block.
+func readHeaderBlocks(body []byte) []textproto.MIMEHeader {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(body)))
+	var blocks []textproto.MIMEHeader
+	for {
+		fields, err := reader.ReadMIMEHeader()
+		if len(fields) > 0 {
+			blocks = append(blocks, fields)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if len(blocks) == 0 {
+		blocks = append(blocks, textproto.MIMEHeader{})
+	}
+	return blocks
+}
+
+// dsnAddress returns the address of a recipient field such as "rfc822; user@example.com"
+func dsnAddress(field string) string {
+	if i := strings.Index(field, ";"); i >= 0 {
+		field = field[i+1:]
+	}
+	return strings.Trim(strings.TrimSpace(field), "<>")
+}
+
+// decodePart undoes a base64 transfer encoding; multipart.Reader already decodes
+// quoted-printable parts
+func decodePart(part *multipart.Part) io.Reader {
+	if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+		return base64.NewDecoder(base64.StdEncoding, part)
+	}
+	return part
+}
@@ -0,0 +1,84 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default location of messages written by the file transport
+const defaultMailFilePath = "./data/mail"
+
+// fileTransportMu serializes writes so concurrent workers don't interleave mbox entries
+var fileTransportMu sync.Mutex
+
+// FileTransport writes messages to disk instead of sending them, for development and tests.
+// A path ending in .mbox appends every message to that mailbox file; any other path is a
+// directory that gets one .eml file per message.
+type FileTransport struct {
+	Path string
+}
+
+// Send writes the message to the configured file or directory
+func (t *FileTransport) Send(msg *Message) error {
+	path := t.Path
+	if path == "" {
+		path = defaultMailFilePath
+	}
+
+	fileTransportMu.Lock()
+	defer fileTransportMu.Unlock()
+
+	if strings.HasSuffix(path, ".mbox") {
+		return appendToMbox(path, msg)
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), sanitizeFileName(msg.To))
+	return os.WriteFile(filepath.Join(path, name), msg.Bytes(), 0644)
+}
+
+// appendToMbox appends a message in mboxrd format
+func appendToMbox(path string, msg *Message) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var entry bytes.Buffer
+	entry.WriteString(fmt.Sprintf("From %s %s\n", msg.FromEmail, time.Now().UTC().Format(time.ANSIC)))
+	for _, line := range strings.Split(strings.ReplaceAll(string(msg.Bytes()), "\r\n", "\n"), "\n") {
+		// Quote lines that would otherwise start a new message
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = ">" + line
+		}
+		entry.WriteString(line)
+		entry.WriteString("\n")
+	}
+	entry.WriteString("\n")
+
+	_, err = file.Write(entry.Bytes())
+	return err
+}
+
+// sanitizeFileName keeps an email address usable as part of a file name
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '@' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
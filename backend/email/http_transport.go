@@ -0,0 +1,296 @@
+package email
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Timeout for a single provider API request
+	providerTimeout = 15 * time.Second
+	// Provider error bodies are truncated to this many bytes
+	providerResponseLimit = 2048
+)
+
+// providerClient is shared by all HTTP transports
+var providerClient = &http.Client{Timeout: providerTimeout}
+
+// PostmarkTransport sends messages through the Postmark API
+type PostmarkTransport struct {
+	ServerToken string
+	Endpoint    string // defaults to https://api.postmarkapp.com
+}
+
+// Send posts the message to Postmark's single email endpoint
+func (t *PostmarkTransport) Send(msg *Message) error {
+	type header struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	}
+	headers := []header{}
+	for _, name := range msg.headerNames() {
+		headers = append(headers, header{Name: name, Value: msg.Headers[name]})
+	}
+
+	type attachment struct {
+		Name        string `json:"Name"`
+		Content     string `json:"Content"`
+		ContentType string `json:"ContentType"`
+		ContentID   string `json:"ContentID"`
+	}
+	attachments := []attachment{}
+	for _, image := range msg.Inline {
+		attachments = append(attachments, attachment{
+			Name:        image.Filename,
+			Content:     base64.StdEncoding.EncodeToString(image.Data),
+			ContentType: image.ContentType,
+			ContentID:   "cid:" + image.ContentID,
+		})
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"From":        msg.From(),
+		"To":          msg.To,
+		"Subject":     msg.Subject,
+		"HtmlBody":    msg.HTML,
+		"TextBody":    msg.Text,
+		"Headers":     headers,
+		"Attachments": attachments,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpointURL(t.Endpoint, "https://api.postmarkapp.com")+"/email", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Postmark-Server-Token", t.ServerToken)
+
+	return doProviderRequest(ProviderPostmark, msg.To, req)
+}
+
+// SendGridTransport sends messages through the SendGrid v3 API
+type SendGridTransport struct {
+	APIKey   string
+	Endpoint string // defaults to https://api.sendgrid.com
+}
+
+// Send posts the message to SendGrid's mail send endpoint
+func (t *SendGridTransport) Send(msg *Message) error {
+	// SendGrid requires text/plain before text/html
+	content := []map[string]string{}
+	if msg.Text != "" {
+		content = append(content, map[string]string{"type": "text/plain", "value": msg.Text})
+	}
+	if msg.HTML != "" {
+		content = append(content, map[string]string{"type": "text/html", "value": msg.HTML})
+	}
+
+	body := map[string]interface{}{
+		"personalizations": []map[string]interface{}{
+			{"to": []map[string]string{{"email": msg.To}}},
+		},
+		"from":    map[string]string{"email": msg.FromEmail, "name": msg.FromName},
+		"subject": msg.Subject,
+		"content": content,
+	}
+	if len(msg.Headers) > 0 {
+		body["headers"] = msg.Headers
+	}
+	if len(msg.Inline) > 0 {
+		attachments := []map[string]string{}
+		for _, image := range msg.Inline {
+			attachments = append(attachments, map[string]string{
+				"content":     base64.StdEncoding.EncodeToString(image.Data),
+				"type":        image.ContentType,
+				"filename":    image.Filename,
+				"disposition": "inline",
+				"content_id":  image.ContentID,
+			})
+		}
+		body["attachments"] = attachments
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpointURL(t.Endpoint, "https://api.sendgrid.com")+"/v3/mail/send", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.APIKey)
+
+	return doProviderRequest(ProviderSendGrid, msg.To, req)
+}
+
+// MailgunTransport sends messages through the Mailgun messages API
+type MailgunTransport struct {
+	APIKey   string
+	Domain   string // sending domain
+	Endpoint string // defaults to https://api.mailgun.net, use https://api.eu.mailgun.net for EU domains
+}
+
+// Send posts the message as a multipart form to Mailgun's messages endpoint.
+// Inline images are uploaded as files; Mailgun uses the file name as content ID.
+func (t *MailgunTransport) Send(msg *Message) error {
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	writer.WriteField("from", msg.From())
+	writer.WriteField("to", msg.To)
+	writer.WriteField("subject", msg.Subject)
+	if msg.HTML != "" {
+		writer.WriteField("html", msg.HTML)
+	}
+	if msg.Text != "" {
+		writer.WriteField("text", msg.Text)
+	}
+	for _, name := range msg.headerNames() {
+		writer.WriteField("h:"+name, msg.Headers[name])
+	}
+	for _, image := range msg.Inline {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Disposition": {mime.FormatMediaType("form-data", map[string]string{"name": "inline", "filename": image.ContentID})},
+			"Content-Type":        {image.ContentType},
+		})
+		if err != nil {
+			return err
+		}
+		part.Write(image.Data)
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v3/%s/messages", endpointURL(t.Endpoint, "https://api.mailgun.net"), url.PathEscape(t.Domain))
+	req, err := http.NewRequest(http.MethodPost, endpoint, &form)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.SetBasicAuth("api", t.APIKey)
+
+	return doProviderRequest(ProviderMailgun, msg.To, req)
+}
+
+// SESTransport sends messages through the Amazon SES v2 API as raw MIME messages
+type SESTransport struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string
+	Endpoint        string // defaults to https://email.<region>.amazonaws.com
+}
+
+// Send posts the raw message to the SES outbound emails endpoint, signed with SigV4
+func (t *SESTransport) Send(msg *Message) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"FromEmailAddress": msg.From(),
+		"Destination": map[string][]string{
+			"ToAddresses": {msg.To},
+		},
+		"Content": map[string]interface{}{
+			"Raw": map[string]string{"Data": base64.StdEncoding.EncodeToString(msg.Bytes())},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	base := endpointURL(t.Endpoint, fmt.Sprintf("https://email.%s.amazonaws.com", t.Region))
+	req, err := http.NewRequest(http.MethodPost, base+"/v2/email/outbound-emails", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signSigV4(req, payload, t.AccessKeyID, t.SecretAccessKey, t.Region, "ses", time.Now().UTC())
+
+	return doProviderRequest(ProviderSES, msg.To, req)
+}
+
+// signSigV4 adds AWS Signature Version 4 headers to a request
+func signSigV4(req *http.Request, payload []byte, accessKeyID, secretAccessKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("content-type:%s\nhost:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n",
+		req.Header.Get("Content-Type"), req.URL.Host, payloadHash, amzDate)
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.RawQuery, canonicalHeaders, signedHeaders, payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, region, service)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), dateStamp)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+// doProviderRequest sends a provider API request and turns non-2xx responses into a ProviderError
+func doProviderRequest(provider, recipient string, req *http.Request) error {
+	resp, err := providerClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, providerResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &ProviderError{Provider: provider, Recipient: recipient, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return nil
+}
+
+// endpointURL returns the configured API endpoint without trailing slash, or the provider default
+func endpointURL(configured, fallback string) string {
+	if configured == "" {
+		return fallback
+	}
+	return strings.TrimRight(configured, "/")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
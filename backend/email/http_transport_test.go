@@ -0,0 +1,327 @@
+package email
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// capturedRequest is what a stub provider received
+type capturedRequest struct {
+	request *http.Request
+	body    []byte
+}
+
+// stubProvider starts a server answering every request with status and body
+func stubProvider(t *testing.T, status int, body string) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+		}
+		captured.request = r
+		captured.body = data
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, captured
+}
+
+func testMessage() *Message {
+	return &Message{
+		FromName:  "Ship Team",
+		FromEmail: "team@example.com",
+		To:        "reader@example.org",
+		Subject:   "Release 1.2",
+		HTML:      "<p>Hello</p>",
+		Text:      "Hello",
+		Headers:   map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+		MessageID: "<id@example.com>",
+	}
+}
+
+func TestPostmarkTransportSend(t *testing.T) {
+	server, captured := stubProvider(t, http.StatusOK, `{"ErrorCode":0}`)
+	transport := &PostmarkTransport{ServerToken: "pm-token", Endpoint: server.URL + "/"}
+
+	if err := transport.Send(testMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if captured.request.URL.Path != "/email" {
+		t.Errorf("path = %q, want /email", captured.request.URL.Path)
+	}
+	if got := captured.request.Header.Get("X-Postmark-Server-Token"); got != "pm-token" {
+		t.Errorf("server token = %q, want pm-token", got)
+	}
+
+	var body struct {
+		From     string
+		To       string
+		Subject  string
+		HtmlBody string
+		TextBody string
+		Headers  []struct{ Name, Value string }
+	}
+	if err := json.Unmarshal(captured.body, &body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.From != `"Ship Team" <team@example.com>` || body.To != "reader@example.org" || body.Subject != "Release 1.2" {
+		t.Errorf("addressing = %q / %q / %q", body.From, body.To, body.Subject)
+	}
+	if body.HtmlBody != "<p>Hello</p>" || body.TextBody != "Hello" {
+		t.Errorf("bodies = %q / %q", body.HtmlBody, body.TextBody)
+	}
+	if len(body.Headers) != 1 || body.Headers[0].Name != "List-Unsubscribe" {
+		t.Errorf("headers = %+v", body.Headers)
+	}
+}
+
+func TestSendGridTransportSend(t *testing.T) {
+	server, captured := stubProvider(t, http.StatusAccepted, "")
+	transport := &SendGridTransport{APIKey: "sg-key", Endpoint: server.URL}
+
+	if err := transport.Send(testMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if captured.request.URL.Path != "/v3/mail/send" {
+		t.Errorf("path = %q, want /v3/mail/send", captured.request.URL.Path)
+	}
+	if got := captured.request.Header.Get("Authorization"); got != "Bearer sg-key" {
+		t.Errorf("authorization = %q, want Bearer sg-key", got)
+	}
+
+	var body struct {
+		Personalizations []struct {
+			To []struct{ Email string } `json:"to"`
+		} `json:"personalizations"`
+		From    struct{ Email, Name string }   `json:"from"`
+		Subject string                         `json:"subject"`
+		Content []struct{ Type, Value string } `json:"content"`
+		Headers map[string]string              `json:"headers"`
+	}
+	if err := json.Unmarshal(captured.body, &body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if len(body.Personalizations) != 1 || len(body.Personalizations[0].To) != 1 || body.Personalizations[0].To[0].Email != "reader@example.org" {
+		t.Errorf("personalizations = %+v", body.Personalizations)
+	}
+	if body.From.Email != "team@example.com" || body.From.Name != "Ship Team" || body.Subject != "Release 1.2" {
+		t.Errorf("from/subject = %+v / %q", body.From, body.Subject)
+	}
+	// SendGrid rejects content where text/plain doesn't come first
+	if len(body.Content) != 2 || body.Content[0].Type != "text/plain" || body.Content[1].Type != "text/html" {
+		t.Errorf("content = %+v", body.Content)
+	}
+	if body.Headers["List-Unsubscribe"] != "<https://example.com/u>" {
+		t.Errorf("headers = %+v", body.Headers)
+	}
+}
+
+func TestMailgunTransportSend(t *testing.T) {
+	server, captured := stubProvider(t, http.StatusOK, `{"id":"<1@mg>"}`)
+	transport := &MailgunTransport{APIKey: "mg-key", Domain: "mg.example.com", Endpoint: server.URL}
+
+	msg := testMessage()
+	msg.Inline = []InlineImage{{ContentID: "logo.png", Filename: "logo.png", ContentType: "image/png", Data: []byte("png")}}
+	if err := transport.Send(msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if captured.request.URL.Path != "/v3/mg.example.com/messages" {
+		t.Errorf("path = %q, want /v3/mg.example.com/messages", captured.request.URL.Path)
+	}
+	user, password, ok := captured.request.BasicAuth()
+	if !ok || user != "api" || password != "mg-key" {
+		t.Errorf("basic auth = %q:%q (%v), want api:mg-key", user, password, ok)
+	}
+
+	// Parse the form again from the captured body
+	captured.request.Body = io.NopCloser(strings.NewReader(string(captured.body)))
+	if err := captured.request.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("parsing form: %v", err)
+	}
+	form := captured.request.MultipartForm
+	want := map[string]string{
+		"from":               `"Ship Team" <team@example.com>`,
+		"to":                 "reader@example.org",
+		"subject":            "Release 1.2",
+		"html":               "<p>Hello</p>",
+		"text":               "Hello",
+		"h:List-Unsubscribe": "<https://example.com/u>",
+	}
+	for field, value := range want {
+		if got := form.Value[field]; len(got) != 1 || got[0] != value {
+			t.Errorf("form field %s = %q, want %q", field, got, value)
+		}
+	}
+	if files := form.File["inline"]; len(files) != 1 || files[0].Filename != "logo.png" {
+		t.Errorf("inline files = %+v", files)
+	}
+}
+
+func TestSESTransportSend(t *testing.T) {
+	server, captured := stubProvider(t, http.StatusOK, `{"MessageId":"1"}`)
+	transport := &SESTransport{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", Region: "eu-west-1", Endpoint: server.URL}
+
+	if err := transport.Send(testMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	r := captured.request
+	if r.URL.Path != "/v2/email/outbound-emails" {
+		t.Errorf("path = %q, want /v2/email/outbound-emails", r.URL.Path)
+	}
+
+	sum := sha256.Sum256(captured.body)
+	if got := r.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("content hash = %q, want hash of the body", got)
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		t.Fatalf("X-Amz-Date = %q", amzDate)
+	}
+	scope := amzDate[:8] + "/eu-west-1/ses/aws4_request"
+	prefix := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/" + scope + ", SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, prefix) {
+		t.Fatalf("authorization = %q, want prefix %q", authorization, prefix)
+	}
+
+	// Verify the signature the way SES does, from the request as received
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		fmt.Sprintf("content-type:%s\nhost:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n",
+			r.Header.Get("Content-Type"), r.Host, r.Header.Get("X-Amz-Content-Sha256"), amzDate),
+		"content-type;host;x-amz-content-sha256;x-amz-date",
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := hmacSHA256([]byte("AWS4secret"), amzDate[:8])
+	for _, part := range []string{"eu-west-1", "ses", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); strings.TrimPrefix(authorization, prefix) != want {
+		t.Errorf("signature = %q, want %q", strings.TrimPrefix(authorization, prefix), want)
+	}
+
+	var body struct {
+		FromEmailAddress string
+		Destination      struct{ ToAddresses []string }
+		Content          struct{ Raw struct{ Data string } }
+	}
+	if err := json.Unmarshal(captured.body, &body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if len(body.Destination.ToAddresses) != 1 || body.Destination.ToAddresses[0] != "reader@example.org" {
+		t.Errorf("destination = %+v", body.Destination)
+	}
+	raw, err := base64.StdEncoding.DecodeString(body.Content.Raw.Data)
+	if err != nil || !strings.Contains(string(raw), "Subject: Release 1.2") {
+		t.Errorf("raw message = %q (%v)", raw, err)
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		name              string
+		transport         func(endpoint string) Transport
+		status            int
+		body              string
+		permanent         bool
+		recipientRejected bool
+	}{
+		{
+			name:              "postmark inactive recipient",
+			transport:         func(e string) Transport { return &PostmarkTransport{ServerToken: "x", Endpoint: e} },
+			status:            http.StatusUnprocessableEntity,
+			body:              `{"ErrorCode":406,"Message":"You tried to send to recipient(s) that have been marked as inactive."}`,
+			permanent:         true,
+			recipientRejected: true,
+		},
+		{
+			name:      "postmark unverified sender",
+			transport: func(e string) Transport { return &PostmarkTransport{ServerToken: "x", Endpoint: e} },
+			status:    http.StatusUnprocessableEntity,
+			body:      `{"ErrorCode":400,"Message":"The 'From' address you supplied is not a Sender Signature."}`,
+			permanent: true,
+		},
+		{
+			name:              "sendgrid invalid recipient",
+			transport:         func(e string) Transport { return &SendGridTransport{APIKey: "x", Endpoint: e} },
+			status:            http.StatusBadRequest,
+			body:              `{"errors":[{"message":"Does not contain a valid address: reader@example.org","field":"personalizations.0.to.0.email"}]}`,
+			permanent:         true,
+			recipientRejected: true,
+		},
+		{
+			name:      "sendgrid bad api key",
+			transport: func(e string) Transport { return &SendGridTransport{APIKey: "x", Endpoint: e} },
+			status:    http.StatusUnauthorized,
+			body:      `{"errors":[{"message":"The provided authorization grant is invalid"}]}`,
+		},
+		{
+			name:      "mailgun server error",
+			transport: func(e string) Transport { return &MailgunTransport{APIKey: "x", Domain: "d", Endpoint: e} },
+			status:    http.StatusInternalServerError,
+			body:      "reader@example.org",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := stubProvider(t, tt.status, tt.body)
+			err := tt.transport(server.URL).Send(testMessage())
+
+			var providerErr *ProviderError
+			if !errors.As(err, &providerErr) || providerErr.StatusCode != tt.status {
+				t.Fatalf("error = %v, want provider error with status %d", err, tt.status)
+			}
+			if got := IsPermanentError(err); got != tt.permanent {
+				t.Errorf("IsPermanentError = %v, want %v", got, tt.permanent)
+			}
+			if got := IsRecipientRejected(err); got != tt.recipientRejected {
+				t.Errorf("IsRecipientRejected = %v, want %v", got, tt.recipientRejected)
+			}
+		})
+	}
+}
+
+func TestIsRecipientRejectedSMTP(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unknown user", &SMTPError{Recipient: "a@example.org", Command: "RCPT TO", Err: &textproto.Error{Code: 550, Msg: "5.1.1 User unknown"}}, true},
+		{"no enhanced code", &SMTPError{Recipient: "a@example.org", Command: "RCPT TO", Err: &textproto.Error{Code: 550, Msg: "No such user"}}, true},
+		{"relaying denied", &SMTPError{Recipient: "a@example.org", Command: "RCPT TO", Err: &textproto.Error{Code: 550, Msg: "5.7.1 Relaying denied"}}, false},
+		{"mailbox full", &SMTPError{Recipient: "a@example.org", Command: "RCPT TO", Err: &textproto.Error{Code: 452, Msg: "4.2.2 Mailbox full"}}, false},
+		{"sender rejected", &SMTPError{Recipient: "a@example.org", Command: "MAIL FROM", Err: &textproto.Error{Code: 550, Msg: "5.1.8 Bad sender"}}, false},
+		{"authentication failed", &textproto.Error{Code: 535, Msg: "5.7.8 Authentication failed"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRecipientRejected(tt.err); got != tt.want {
+				t.Errorf("IsRecipientRejected(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
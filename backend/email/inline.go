@@ -0,0 +1,85 @@
+package email
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// UploadsDir is where uploaded images referenced by emails are read from
+var UploadsDir = "./data/uploads"
+
+const (
+	// Larger uploads stay linked instead of being embedded
+	maxInlineImageSize = 1 << 20
+	// Upper bound for all embedded images of one message
+	maxInlineTotalSize = 3 << 20
+)
+
+// InlineImage is an image embedded in the message and referenced from the HTML as cid:ContentID
+type InlineImage struct {
+	ContentID   string
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+var imgSrcRegex = regexp.MustCompile(`(?i)(<img\b[^>]*?\bsrc\s*=\s*)(["'])([^"']+)(["'])`)
+
+// embedUploadedImages replaces <img> sources pointing at /api/uploads/ or /uploads/ with
+// cid: references and attaches the files, so images show without loading remote content
+func (m *Message) embedUploadedImages() {
+	total := 0
+	embedded := map[string]bool{}
+
+	m.HTML = imgSrcRegex.ReplaceAllStringFunc(m.HTML, func(tag string) string {
+		parts := imgSrcRegex.FindStringSubmatch(tag)
+		filename := uploadFilename(parts[3])
+		if filename == "" {
+			return tag
+		}
+
+		if !embedded[filename] {
+			data, err := os.ReadFile(filepath.Join(UploadsDir, filename))
+			if err != nil || len(data) > maxInlineImageSize || total+len(data) > maxInlineTotalSize {
+				return tag
+			}
+			contentType := http.DetectContentType(data)
+			if !strings.HasPrefix(contentType, "image/") {
+				return tag
+			}
+
+			total += len(data)
+			embedded[filename] = true
+			m.Inline = append(m.Inline, InlineImage{
+				ContentID:   filename,
+				Filename:    filename,
+				ContentType: contentType,
+				Data:        data,
+			})
+		}
+
+		return parts[1] + parts[2] + "cid:" + filename + parts[4]
+	})
+}
+
+// uploadFilename returns the uploaded file name an image URL points at, or "" for other URLs
+func uploadFilename(src string) string {
+	parsed, err := url.Parse(strings.TrimSpace(src))
+	if err != nil || (parsed.Scheme != "" && parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ""
+	}
+
+	dir, filename := path.Split(parsed.Path)
+	if dir != "/api/uploads/" && dir != "/uploads/" {
+		return ""
+	}
+	if filename == "" || filename == "." || filename == ".." || strings.Contains(filename, "\\") || !strings.Contains(filename, ".") {
+		return ""
+	}
+	return filename
+}
@@ -0,0 +1,245 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Headers set by the MIME builder that extra headers may not override
+var reservedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Message-Id":                true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+}
+
+// ErrInvalidMessage is wrapped by every Validate error
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Validate rejects messages whose addresses or headers could inject extra headers or recipients
+func (m *Message) Validate() error {
+	for field, value := range map[string]string{"from name": m.FromName, "subject": m.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: %s must not contain line breaks", ErrInvalidMessage, field)
+		}
+	}
+	if err := validateAddress(m.FromEmail); err != nil {
+		return fmt.Errorf("%w: from email: %v", ErrInvalidMessage, err)
+	}
+	if err := validateAddress(m.To); err != nil {
+		return fmt.Errorf("%w: recipient: %v", ErrInvalidMessage, err)
+	}
+
+	for name, value := range m.Headers {
+		if !isHeaderName(name) {
+			return fmt.Errorf("%w: header name %q", ErrInvalidMessage, name)
+		}
+		if reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			return fmt.Errorf("%w: header %s cannot be overridden", ErrInvalidMessage, name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: %s header must not contain line breaks", ErrInvalidMessage, name)
+		}
+	}
+	return nil
+}
+
+// Prepare validates the message and fills in everything derived from it: the plain-text
+// alternative, the Message-ID and inline images for uploaded files
+func (m *Message) Prepare() error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if m.Text == "" && m.HTML != "" {
+		m.Text = HTMLToText(m.HTML)
+	}
+	if m.MessageID == "" {
+		m.MessageID = newMessageID(m.FromEmail)
+	}
+	if m.HTML != "" && len(m.Inline) == 0 {
+		m.embedUploadedImages()
+	}
+	return nil
+}
+
+// Bytes renders the message in RFC 5322 / MIME format for SMTP, SES and file transports.
+// HTML messages become multipart/alternative with a plain-text part, and inline images are
+// wrapped with the HTML in multipart/related.
+func (m *Message) Bytes() []byte {
+	messageID := m.MessageID
+	if messageID == "" {
+		messageID = newMessageID(m.FromEmail)
+	}
+
+	var head bytes.Buffer
+	writeHeader(&head, "From", m.From())
+	writeHeader(&head, "To", (&mail.Address{Address: stripLineBreaks(m.To)}).String())
+	writeHeader(&head, "Subject", encodeHeaderValue(m.Subject))
+	writeHeader(&head, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&head, "Message-ID", messageID)
+	for _, name := range m.headerNames() {
+		if !isHeaderName(name) || reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			continue
+		}
+		writeHeader(&head, name, encodeHeaderValue(m.Headers[name]))
+	}
+	writeHeader(&head, "MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		writeHeader(&head, "Content-Type", "text/plain; charset=UTF-8")
+		writeHeader(&head, "Content-Transfer-Encoding", "quoted-printable")
+		head.WriteString("\r\n")
+		head.Write(quotedPrintable(m.Text))
+		return head.Bytes()
+	}
+
+	text := m.Text
+	if text == "" {
+		text = HTMLToText(m.HTML)
+	}
+
+	var body bytes.Buffer
+	alternative := multipart.NewWriter(&body)
+	writeHeader(&head, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", alternative.Boundary()))
+	head.WriteString("\r\n")
+
+	textPart, _ := alternative.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	textPart.Write(quotedPrintable(text))
+
+	if len(m.Inline) == 0 {
+		htmlPart, _ := alternative.CreatePart(htmlPartHeader())
+		htmlPart.Write(quotedPrintable(m.HTML))
+	} else {
+		var related bytes.Buffer
+		relatedWriter := multipart.NewWriter(&related)
+
+		htmlPart, _ := relatedWriter.CreatePart(htmlPartHeader())
+		htmlPart.Write(quotedPrintable(m.HTML))
+		for _, image := range m.Inline {
+			imagePart, _ := relatedWriter.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {mime.FormatMediaType(image.ContentType, map[string]string{"name": image.Filename})},
+				"Content-Transfer-Encoding": {"base64"},
+				"Content-Id":                {"<" + image.ContentID + ">"},
+				"Content-Disposition":       {mime.FormatMediaType("inline", map[string]string{"filename": image.Filename})},
+			})
+			imagePart.Write(base64Lines(image.Data))
+		}
+		relatedWriter.Close()
+
+		relatedPart, _ := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {fmt.Sprintf("multipart/related; boundary=%q", relatedWriter.Boundary())},
+		})
+		relatedPart.Write(related.Bytes())
+	}
+	alternative.Close()
+
+	head.Write(body.Bytes())
+	return head.Bytes()
+}
+
+func htmlPartHeader() textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}
+}
+
+// writeHeader writes a single header line, dropping any line breaks from the value
+func writeHeader(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	b.WriteString(": ")
+	b.WriteString(stripLineBreaks(value))
+	b.WriteString("\r\n")
+}
+
+// encodeHeaderValue RFC 2047 encodes values that are not plain ASCII
+func encodeHeaderValue(value string) string {
+	return mime.QEncoding.Encode("UTF-8", stripLineBreaks(value))
+}
+
+func stripLineBreaks(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+func quotedPrintable(s string) []byte {
+	var b bytes.Buffer
+	w := quotedprintable.NewWriter(&b)
+	w.Write([]byte(strings.ReplaceAll(s, "\r\n", "\n")))
+	w.Close()
+	return b.Bytes()
+}
+
+// base64Lines encodes data as base64 wrapped at 76 characters per line
+func base64Lines(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b bytes.Buffer
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// newMessageID returns a unique Message-ID on the sender's domain
+func newMessageID(fromEmail string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(fromEmail, "@"); at != -1 && at < len(fromEmail)-1 {
+		domain = stripLineBreaks(fromEmail[at+1:])
+	}
+
+	random := make([]byte, 16)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
+
+// validateAddress accepts a bare email address only, no display name or address list
+func validateAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("address is empty")
+	}
+	if strings.ContainsAny(address, "\r\n") {
+		return fmt.Errorf("address must not contain line breaks")
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	if parsed.Address != address {
+		return fmt.Errorf("%q is not a plain email address", address)
+	}
+	return nil
+}
+
+// isHeaderName checks a header field name against RFC 5322 (printable ASCII except colon)
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r > '~' || r == ':' {
+			return false
+		}
+	}
+	return true
+}
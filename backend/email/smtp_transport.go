@@ -0,0 +1,311 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Timeout for connecting to the SMTP server
+	smtpDialTimeout = 10 * time.Second
+	// Deadline for sending one message over an open connection
+	smtpSendTimeout = time.Minute
+	// Idle connections are closed after this long, well below typical server timeouts
+	smtpIdleTimeout = 30 * time.Second
+	// Idle connections kept per server; extra connections are closed when returned
+	smtpMaxIdleConns = 8
+	// Connections are replaced after this many messages, many servers cap it
+	smtpMaxMessagesPerConn = 100
+)
+
+// SMTPTransport sends messages through an SMTP server, reusing authenticated connections
+// across messages (RSET between them) so bulk sends don't reconnect for every recipient
+type SMTPTransport struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	Encryption string // tls (STARTTLS), ssl or none
+}
+
+// SMTPError reports the SMTP command a message failed at for its recipient
+type SMTPError struct {
+	Recipient string
+	Command   string // MAIL FROM, RCPT TO or DATA
+	Err       error
+}
+
+func (e *SMTPError) Error() string {
+	return fmt.Sprintf("smtp %s failed for %s: %v", e.Command, e.Recipient, e.Err)
+}
+
+func (e *SMTPError) Unwrap() error {
+	return e.Err
+}
+
+// Enhanced status codes (RFC 3463) at the start of an SMTP reply, such as 5.1.1
+var enhancedCodeRegex = regexp.MustCompile(`^[245]\.\d{1,3}\.\d{1,3}\b`)
+
+// EnhancedCode returns the enhanced status code of the server's reply, if it sent one
+func (e *SMTPError) EnhancedCode() string {
+	var protoErr *textproto.Error
+	if !errors.As(e.Err, &protoErr) {
+		return ""
+	}
+	return enhancedCodeRegex.FindString(strings.TrimSpace(protoErr.Msg))
+}
+
+// Send delivers a message over a pooled connection. A broken reused connection is
+// replaced and the message sent again, unless the server may already have accepted it.
+func (t *SMTPTransport) Send(msg *Message) error {
+	pool := smtpPoolFor(t)
+	data := msg.Bytes()
+
+	for attempt := 0; ; attempt++ {
+		conn, reused, err := pool.get(t)
+		if err != nil {
+			return err
+		}
+
+		err = conn.send(msg.FromEmail, msg.To, data)
+		if err == nil {
+			pool.put(conn)
+			return nil
+		}
+
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) {
+			// The server rejected this message; the connection stays usable after RSET
+			pool.put(conn)
+			return err
+		}
+
+		conn.close()
+		var smtpErr *SMTPError
+		ambiguous := errors.As(err, &smtpErr) && smtpErr.Command == "DATA" && conn.dataWritten
+		if !reused || attempt > 0 || ambiguous {
+			return err
+		}
+	}
+}
+
+// smtpConn is one authenticated connection to an SMTP server
+type smtpConn struct {
+	conn        net.Conn
+	client      *smtp.Client
+	sent        int
+	lastUsed    time.Time
+	dataWritten bool // the last message was fully written, the server may have accepted it
+}
+
+// send runs one mail transaction on the connection
+func (c *smtpConn) send(from, to string, data []byte) error {
+	c.dataWritten = false
+	c.conn.SetDeadline(time.Now().Add(smtpSendTimeout))
+
+	if err := c.client.Mail(from); err != nil {
+		return &SMTPError{Recipient: to, Command: "MAIL FROM", Err: err}
+	}
+	if err := c.client.Rcpt(to); err != nil {
+		return &SMTPError{Recipient: to, Command: "RCPT TO", Err: err}
+	}
+
+	writer, err := c.client.Data()
+	if err != nil {
+		return &SMTPError{Recipient: to, Command: "DATA", Err: err}
+	}
+	if _, err := writer.Write(data); err != nil {
+		return &SMTPError{Recipient: to, Command: "DATA", Err: err}
+	}
+	c.dataWritten = true
+	if err := writer.Close(); err != nil {
+		return &SMTPError{Recipient: to, Command: "DATA", Err: err}
+	}
+
+	c.sent++
+	return nil
+}
+
+// quit ends the session politely and closes the connection
+func (c *smtpConn) quit() {
+	c.conn.SetDeadline(time.Now().Add(smtpDialTimeout))
+	if err := c.client.Quit(); err != nil {
+		c.client.Close()
+	}
+}
+
+func (c *smtpConn) close() {
+	c.client.Close()
+}
+
+// smtpPool keeps idle connections to one SMTP server with one set of credentials
+type smtpPool struct {
+	mu   sync.Mutex
+	idle []*smtpConn
+}
+
+var (
+	smtpPoolsMu     sync.Mutex
+	smtpPools       = map[string]*smtpPool{}
+	smtpJanitorOnce sync.Once
+)
+
+// smtpPoolFor returns the pool for the transport's server and credentials.
+// Changing the mail settings starts a new pool; the old one drains through the janitor.
+func smtpPoolFor(t *SMTPTransport) *smtpPool {
+	key := strings.Join([]string{
+		strings.ToLower(t.Host), fmt.Sprint(t.Port), strings.ToLower(t.Encryption), t.Username, sha256Hex([]byte(t.Password)),
+	}, "|")
+
+	smtpJanitorOnce.Do(func() { go smtpJanitor() })
+
+	smtpPoolsMu.Lock()
+	defer smtpPoolsMu.Unlock()
+	pool, ok := smtpPools[key]
+	if !ok {
+		pool = &smtpPool{}
+		smtpPools[key] = pool
+	}
+	return pool
+}
+
+// get returns a healthy idle connection reset with RSET, or dials a new one
+func (p *smtpPool) get(t *SMTPTransport) (*smtpConn, bool, error) {
+	for {
+		p.mu.Lock()
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			break
+		}
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+
+		if time.Since(conn.lastUsed) > smtpIdleTimeout {
+			conn.close()
+			continue
+		}
+		conn.conn.SetDeadline(time.Now().Add(smtpDialTimeout))
+		if err := conn.client.Reset(); err != nil {
+			// Stale connection, the server probably timed it out
+			conn.close()
+			continue
+		}
+		return conn, true, nil
+	}
+
+	conn, err := dialSMTP(t)
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, false, nil
+}
+
+// put returns a connection to the pool, or closes it when the pool is full or it is used up
+func (p *smtpPool) put(conn *smtpConn) {
+	conn.lastUsed = time.Now()
+	conn.conn.SetDeadline(time.Time{})
+
+	p.mu.Lock()
+	if conn.sent < smtpMaxMessagesPerConn && len(p.idle) < smtpMaxIdleConns {
+		p.idle = append(p.idle, conn)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	conn.quit()
+}
+
+// closeExpired closes connections that have been idle for too long
+func (p *smtpPool) closeExpired() {
+	p.mu.Lock()
+	var expired []*smtpConn
+	kept := p.idle[:0]
+	for _, conn := range p.idle {
+		if time.Since(conn.lastUsed) > smtpIdleTimeout {
+			expired = append(expired, conn)
+		} else {
+			kept = append(kept, conn)
+		}
+	}
+	p.idle = kept
+	p.mu.Unlock()
+
+	for _, conn := range expired {
+		conn.quit()
+	}
+}
+
+// smtpJanitor periodically closes idle connections so servers don't have to time them out
+func smtpJanitor() {
+	ticker := time.NewTicker(smtpIdleTimeout / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		smtpPoolsMu.Lock()
+		pools := make([]*smtpPool, 0, len(smtpPools))
+		for _, pool := range smtpPools {
+			pools = append(pools, pool)
+		}
+		smtpPoolsMu.Unlock()
+
+		for _, pool := range pools {
+			pool.closeExpired()
+		}
+	}
+}
+
+// dialSMTP opens an authenticated connection. ssl connects over TLS, tls requires
+// STARTTLS, and none still upgrades with STARTTLS when the server offers it.
+func dialSMTP(t *SMTPTransport) (*smtpConn, error) {
+	addr := net.JoinHostPort(t.Host, fmt.Sprint(t.Port))
+	tlsConfig := &tls.Config{ServerName: t.Host}
+	encryption := strings.ToLower(t.Encryption)
+
+	var conn net.Conn
+	var err error
+	if encryption == "ssl" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: smtpDialTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, smtpDialTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(smtpDialTimeout))
+
+	client, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if encryption != "ssl" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		} else if encryption == "tls" {
+			client.Close()
+			return nil, fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+	}
+
+	if t.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return &smtpConn{conn: conn, client: client, lastUsed: time.Now()}, nil
+}
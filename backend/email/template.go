@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"shipshipship/markdown"
 	"shipshipship/models"
 
 	"gorm.io/gorm"
@@ -66,8 +67,8 @@ func GenerateEmailContent(db *gorm.DB, template *models.EmailTemplate, event *mo
 	subject := template.Subject
 	content := template.Content
 
-	// Convert relative image URLs to absolute URLs in event content
-	eventContent := ConvertRelativeUrlsToAbsolute(event.Content, branding.BaseURL)
+	// Render the event content and convert relative image URLs to absolute URLs
+	eventContent := ConvertRelativeUrlsToAbsolute(markdown.ToHTML(event.Content), branding.BaseURL)
 
 	// Generate tags HTML
 	tagsHTML := GenerateTagsHTML(event.Tags)
@@ -85,23 +86,16 @@ func GenerateEmailContent(db *gorm.DB, template *models.EmailTemplate, event *mo
 		eventURL = fmt.Sprintf("/%s", event.Slug)
 	}
 
-	// Use BaseURL for unsubscribe (not ProjectURL which is the external website)
-	unsubscribeURL := fmt.Sprintf("%s/unsubscribe", branding.BaseURL)
-	if branding.BaseURL == "" {
-		unsubscribeURL = "/unsubscribe"
-	}
-
-	// Replace common variables
+	// Replace common variables ({{unsubscribe_url}} is filled in per recipient when sending)
 	replacements := map[string]string{
-		"{{project_name}}":    branding.ProjectName,
-		"{{project_url}}":     branding.ProjectURL,
-		"{{event_name}}":      event.Title,
-		"{{event_url}}":       eventURL,
-		"{{event_content}}":   eventContent,
-		"{{event_date}}":      formattedDateHTML,
-		"{{event_tags}}":      tagsHTML,
-		"{{status}}":          statusDef.DisplayName,
-		"{{unsubscribe_url}}": unsubscribeURL,
+		"{{project_name}}":  branding.ProjectName,
+		"{{project_url}}":   branding.ProjectURL,
+		"{{event_name}}":    event.Title,
+		"{{event_url}}":     eventURL,
+		"{{event_content}}": eventContent,
+		"{{event_date}}":    formattedDateHTML,
+		"{{event_tags}}":    tagsHTML,
+		"{{status}}":        statusDef.DisplayName,
 	}
 
 	// Apply replacements
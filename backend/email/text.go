@@ -0,0 +1,169 @@
+package email
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Elements whose content never shows up in the text rendering
+var skippedTextElements = map[string]bool{
+	"head": true, "title": true, "style": true, "script": true, "noscript": true,
+}
+
+// Elements that start on a new line in the text rendering
+var blockTextElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "table": true, "tr": true, "blockquote": true, "pre": true,
+}
+
+var (
+	blankLinesRegex = regexp.MustCompile(`\n{3,}`)
+	spacesRegex     = regexp.MustCompile(`[ \t\f\r\n]+`)
+)
+
+// HTMLToText renders HTML email content as plain text for the text/plain alternative.
+// Links keep their target in parentheses, images are replaced by their alt text and
+// code blocks keep their line breaks.
+func HTMLToText(content string) string {
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	skipDepth := 0
+	preDepth := 0
+	var links []string // hrefs of currently open <a> elements
+	var linkText []int // text length when each open <a> started
+
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+	}
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name := token.Data
+			if skippedTextElements[name] {
+				if tokenType == html.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+
+			if name == "pre" && tokenType == html.StartTagToken {
+				preDepth++
+			}
+
+			switch {
+			case name == "br":
+				b.WriteString("\n")
+			case name == "hr":
+				newline()
+				b.WriteString("----------\n")
+			case name == "li":
+				newline()
+				b.WriteString("- ")
+			case name == "td" || name == "th":
+				if !strings.HasSuffix(b.String(), "\n") && b.Len() > 0 {
+					b.WriteString(" ")
+				}
+			case name == "img":
+				if alt := attr(token, "alt"); alt != "" {
+					b.WriteString(alt)
+				}
+			case name == "a" && tokenType == html.StartTagToken:
+				links = append(links, untrackedURL(attr(token, "href")))
+				linkText = append(linkText, b.Len())
+			case blockTextElements[name]:
+				newline()
+				if isParagraphElement(name) {
+					b.WriteString("\n")
+				}
+			}
+
+		case html.EndTagToken:
+			name := token.Data
+			if skippedTextElements[name] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+
+			if name == "pre" && preDepth > 0 {
+				preDepth--
+			}
+
+			if name == "a" && len(links) > 0 {
+				href := links[len(links)-1]
+				start := linkText[len(linkText)-1]
+				links = links[:len(links)-1]
+				linkText = linkText[:len(linkText)-1]
+
+				text := strings.TrimSpace(b.String()[start:])
+				if href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(href, "mailto:") && href != text {
+					b.WriteString(" (" + href + ")")
+				}
+			} else if blockTextElements[name] || name == "li" {
+				newline()
+				if isParagraphElement(name) {
+					b.WriteString("\n")
+				}
+			}
+
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			if preDepth > 0 {
+				b.WriteString(strings.TrimSuffix(token.Data, "\n"))
+				continue
+			}
+			text := spacesRegex.ReplaceAllString(token.Data, " ")
+			if strings.HasSuffix(b.String(), "\n") || b.Len() == 0 {
+				text = strings.TrimLeft(text, " ")
+			}
+			b.WriteString(text)
+		}
+	}
+
+	// Trim every line and collapse runs of blank lines
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text := blankLinesRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
+
+// isParagraphElement reports whether an element is set off by blank lines
+func isParagraphElement(name string) bool {
+	switch name {
+	case "p", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "pre":
+		return true
+	}
+	return false
+}
+
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
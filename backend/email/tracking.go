@@ -0,0 +1,86 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// TrackingTokenPlaceholder is replaced with the recipient's signed tracking token when sending
+const TrackingTokenPlaceholder = "{{tracking_token}}"
+
+// Paths of the public tracking endpoints
+const (
+	TrackOpenPath  = "/api/newsletter/track/open"
+	TrackClickPath = "/api/newsletter/track/click"
+)
+
+var (
+	linkHrefRegex = regexp.MustCompile(`(?i)(<a\b[^>]*?\bhref\s*=\s*)(["'])([^"']+)(["'])`)
+	bodyEndRegex  = regexp.MustCompile(`(?i)</body\s*>`)
+)
+
+// InjectTracking routes the http(s) links of newsletter content through the click
+// redirect and adds an open tracking pixel. Unsubscribe and preference links are left
+// alone. Tracking needs absolute URLs, so content is unchanged without a base URL.
+func InjectTracking(content, baseURL string) string {
+	if baseURL == "" {
+		return content
+	}
+
+	content = linkHrefRegex.ReplaceAllStringFunc(content, func(tag string) string {
+		parts := linkHrefRegex.FindStringSubmatch(tag)
+		target := html.UnescapeString(strings.TrimSpace(parts[3]))
+		if !isTrackableLink(target, baseURL) {
+			return tag
+		}
+		return parts[1] + parts[2] + html.EscapeString(ClickTrackingURL(baseURL, target)) + parts[4]
+	})
+
+	pixel := fmt.Sprintf(`<img src="%s%s?t=%s" width="1" height="1" alt="" style="display:block;width:1px;height:1px;border:0;">`,
+		baseURL, TrackOpenPath, TrackingTokenPlaceholder)
+
+	// Insert before the last </body>, or append when the content is a fragment
+	if locs := bodyEndRegex.FindAllStringIndex(content, -1); len(locs) > 0 {
+		last := locs[len(locs)-1][0]
+		return content[:last] + pixel + content[last:]
+	}
+	return content + pixel
+}
+
+// ClickTrackingURL returns the redirect URL that records a click on target
+func ClickTrackingURL(baseURL, target string) string {
+	return fmt.Sprintf("%s%s?t=%s&u=%s", baseURL, TrackClickPath, TrackingTokenPlaceholder, url.QueryEscape(target))
+}
+
+// isTrackableLink reports whether a link should go through the click redirect
+func isTrackableLink(target, baseURL string) bool {
+	lower := strings.ToLower(target)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return false
+	}
+	if strings.Contains(target, "{{") {
+		return false
+	}
+	// Subscription management links must work without the redirect
+	for _, path := range []string{"/unsubscribe", "/newsletter/preferences", "/api/newsletter/"} {
+		if strings.HasPrefix(target, baseURL+path) {
+			return false
+		}
+	}
+	return true
+}
+
+// untrackedURL returns the original target of a click tracking URL, or the URL unchanged
+func untrackedURL(href string) string {
+	parsed, err := url.Parse(href)
+	if err != nil || parsed.Path != TrackClickPath {
+		return href
+	}
+	if target := parsed.Query().Get("u"); target != "" {
+		return target
+	}
+	return href
+}
@@ -0,0 +1,180 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"shipshipship/models"
+)
+
+// Mail providers selectable in the mail settings
+const (
+	ProviderSMTP     = "smtp"
+	ProviderPostmark = "postmark"
+	ProviderSendGrid = "sendgrid"
+	ProviderMailgun  = "mailgun"
+	ProviderSES      = "ses"
+	ProviderFile     = "file" // writes messages to disk instead of sending them
+)
+
+// Providers lists every supported mail provider
+var Providers = []string{ProviderSMTP, ProviderPostmark, ProviderSendGrid, ProviderMailgun, ProviderSES, ProviderFile}
+
+// Message is a single email to one recipient
+type Message struct {
+	FromName  string
+	FromEmail string
+	To        string
+	Subject   string
+	HTML      string            // HTML body, may be empty for plain text emails
+	Text      string            // plain text body, generated from HTML by Prepare when empty
+	Headers   map[string]string // extra headers such as List-Unsubscribe
+	MessageID string            // generated by Prepare
+	Inline    []InlineImage     // images referenced from HTML as cid:, filled by Prepare
+}
+
+// Transport delivers messages through one mail provider
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// ProviderError is returned when an HTTP mail provider rejects a request
+type ProviderError struct {
+	Provider   string
+	Recipient  string
+	StatusCode int
+	Body       string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: unexpected response status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// IsValidProvider checks if a provider name is supported
+func IsValidProvider(provider string) bool {
+	for _, p := range Providers {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTransport returns the transport configured in the mail settings
+func NewTransport(settings *models.MailSettings) (Transport, error) {
+	switch settings.Provider {
+	case ProviderSMTP, "":
+		if settings.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP host must be configured")
+		}
+		return &SMTPTransport{
+			Host:       settings.SMTPHost,
+			Port:       settings.SMTPPort,
+			Username:   settings.SMTPUsername,
+			Password:   settings.SMTPPassword,
+			Encryption: settings.SMTPEncryption,
+		}, nil
+	case ProviderPostmark:
+		if settings.APIKey == "" {
+			return nil, fmt.Errorf("Postmark server token must be configured")
+		}
+		return &PostmarkTransport{ServerToken: settings.APIKey, Endpoint: settings.APIEndpoint}, nil
+	case ProviderSendGrid:
+		if settings.APIKey == "" {
+			return nil, fmt.Errorf("SendGrid API key must be configured")
+		}
+		return &SendGridTransport{APIKey: settings.APIKey, Endpoint: settings.APIEndpoint}, nil
+	case ProviderMailgun:
+		if settings.APIKey == "" || settings.APIDomain == "" {
+			return nil, fmt.Errorf("Mailgun API key and domain must be configured")
+		}
+		return &MailgunTransport{APIKey: settings.APIKey, Domain: settings.APIDomain, Endpoint: settings.APIEndpoint}, nil
+	case ProviderSES:
+		if settings.APIKey == "" || settings.APISecret == "" || settings.APIRegion == "" {
+			return nil, fmt.Errorf("SES access key, secret key and region must be configured")
+		}
+		return &SESTransport{
+			AccessKeyID:     settings.APIKey,
+			SecretAccessKey: settings.APISecret,
+			Region:          settings.APIRegion,
+			Endpoint:        settings.APIEndpoint,
+		}, nil
+	case ProviderFile:
+		return &FileTransport{Path: settings.FilePath}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail provider: %s", settings.Provider)
+	}
+}
+
+// IsPermanentError reports whether sending again won't help without a change: an SMTP
+// 5xx reply or an HTTP provider refusing the request. Unless IsRecipientRejected, this
+// is usually a configuration error such as bad credentials or an unverified sender.
+func IsPermanentError(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 500 && protoErr.Code < 600
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.StatusCode == 400 || providerErr.StatusCode == 422
+	}
+	return false
+}
+
+// IsRecipientRejected reports whether a send failed because of the recipient: an SMTP
+// 5xx reply to RCPT TO other than a relay or policy refusal (5.7.x), or a provider
+// error about the recipient address
+func IsRecipientRejected(err error) bool {
+	var smtpErr *SMTPError
+	if errors.As(err, &smtpErr) {
+		var protoErr *textproto.Error
+		if smtpErr.Command != "RCPT TO" || !errors.As(err, &protoErr) || protoErr.Code < 500 || protoErr.Code >= 600 {
+			return false
+		}
+		return !strings.HasPrefix(smtpErr.EnhancedCode(), "5.7.")
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) && IsPermanentError(err) {
+		return providerErr.namesRecipient()
+	}
+	return false
+}
+
+// Postmark error code for recipients marked inactive after a bounce or complaint
+const postmarkInactiveRecipient = 406
+
+// namesRecipient reports whether the provider's error is about the recipient address
+func (e *ProviderError) namesRecipient() bool {
+	if e.Provider == ProviderPostmark {
+		var body struct {
+			ErrorCode int `json:"ErrorCode"`
+		}
+		if json.Unmarshal([]byte(e.Body), &body) == nil && body.ErrorCode == postmarkInactiveRecipient {
+			return true
+		}
+	}
+	return e.Recipient != "" && strings.Contains(strings.ToLower(e.Body), strings.ToLower(e.Recipient))
+}
+
+// From returns the From address, quoting and RFC 2047 encoding the name as needed
+func (m *Message) From() string {
+	address := mail.Address{Name: stripLineBreaks(m.FromName), Address: stripLineBreaks(m.FromEmail)}
+	return address.String()
+}
+
+// headerNames returns the extra header names in a stable order
+func (m *Message) headerNames() []string {
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
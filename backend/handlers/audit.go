@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAudit writes an audit entry for the current admin user.
+// Failures are logged but never fail the request.
+func recordAudit(c *gin.Context, action, entityType string, entityID interface{}, before, after interface{}) {
+	actor := c.GetString("username")
+
+	if err := models.RecordAudit(database.GetDB(), actor, action, entityType, entityID, before, after); err != nil {
+		fmt.Printf("Warning: Failed to record audit entry for %s %s %v: %v\n", action, entityType, entityID, err)
+	}
+}
+
+// GetAuditLog returns the audit log with pagination and filters (admin only)
+// Query parameters: page, limit, actor, action, entity_type, entity_id, from, to (RFC 3339 or YYYY-MM-DD)
+func GetAuditLog(c *gin.Context) {
+	db := database.GetDB()
+
+	// Parse pagination parameters
+	page := 1
+	limit := 20
+
+	if p := c.Query("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	filter := models.AuditFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+	}
+
+	if from := c.Query("from"); from != "" {
+		parsed, err := parseAuditTime(from, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' date"})
+			return
+		}
+		filter.From = &parsed
+	}
+
+	if to := c.Query("to"); to != "" {
+		parsed, err := parseAuditTime(to, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' date"})
+			return
+		}
+		filter.To = &parsed
+	}
+
+	entries, total, err := models.GetAuditEntries(db, filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":     entries,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// parseAuditTime parses an RFC 3339 timestamp or a plain date.
+// Plain dates used as an upper bound include the whole day.
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"net/http"
 
+	"shipshipship/database"
 	"shipshipship/middleware"
+	"shipshipship/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,7 +16,8 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token string       `json:"token"`
+	User  *models.User `json:"user"`
 }
 
 func Login(c *gin.Context) {
@@ -24,18 +27,20 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	if !middleware.CheckAdminCredentials(req.Username, req.Password) {
+	db := database.GetDB()
+	user, err := models.AuthenticateUser(db, req.Username, req.Password)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	token, err := middleware.GenerateToken(req.Username)
+	token, err := middleware.GenerateToken(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, LoginResponse{Token: token})
+	c.JSON(http.StatusOK, LoginResponse{Token: token, User: user})
 }
 
 func ValidateToken(c *gin.Context) {
@@ -45,9 +50,12 @@ func ValidateToken(c *gin.Context) {
 		return
 	}
 
+	role, _ := c.Get("role")
+
 	c.JSON(http.StatusOK, gin.H{
 		"valid":    true,
 		"username": username,
+		"role":     role,
 	})
 }
 
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+	"shipshipship/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How often the progress stream pushes an update
+const campaignStreamInterval = time.Second
+
+// GetCampaigns returns newsletter campaigns with their progress (?event_id= to filter)
+func GetCampaigns(c *gin.Context) {
+	page, limit := parsePagination(c, 20)
+
+	var eventID uint64
+	if e := c.Query("event_id"); e != "" {
+		parsed, err := strconv.ParseUint(e, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+		eventID = parsed
+	}
+
+	db := database.GetDB()
+	campaigns, total, err := models.GetCampaignsPaginated(db, uint(eventID), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaigns"})
+		return
+	}
+
+	type campaignWithProgress struct {
+		models.EmailCampaign
+		Progress *models.CampaignProgress `json:"progress"`
+	}
+
+	results := make([]campaignWithProgress, len(campaigns))
+	for i, campaign := range campaigns {
+		progress, err := models.GetCampaignProgress(db, campaign.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign progress"})
+			return
+		}
+		results[i] = campaignWithProgress{EmailCampaign: campaign, Progress: progress}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campaigns":   results,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetCampaign returns a campaign with its current progress
+func GetCampaign(c *gin.Context) {
+	campaign, ok := loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	progress, err := models.GetCampaignProgress(database.GetDB(), campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign progress"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campaign": campaign,
+		"progress": progress,
+	})
+}
+
+// StreamCampaignProgress pushes campaign progress as server-sent events until it completes
+func StreamCampaignProgress(c *gin.Context) {
+	campaign, ok := loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+	ticker := time.NewTicker(campaignStreamInterval)
+	defer ticker.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // keep reverse proxies from buffering the stream
+
+	c.Stream(func(w io.Writer) bool {
+		progress, err := models.GetCampaignProgress(db, campaign.ID)
+		if err != nil {
+			c.SSEvent("error", gin.H{"error": "Failed to fetch campaign progress"})
+			return false
+		}
+
+		c.SSEvent("progress", progress)
+		if progress.Finished {
+			return false
+		}
+
+		select {
+		case <-ticker.C:
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// GetCampaignJobs returns the per-recipient status of a campaign (?status= to filter)
+func GetCampaignJobs(c *gin.Context) {
+	campaign, ok := loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	page, limit := parsePagination(c, 50)
+
+	jobs, total, err := models.GetCampaignJobsPaginated(database.GetDB(), campaign.ID, c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":        jobs,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RetryCampaign re-queues the failed recipients of a campaign
+func RetryCampaign(c *gin.Context) {
+	campaign, ok := loadCampaign(c)
+	if !ok {
+		return
+	}
+
+	requeued, err := services.RetryFailedCampaignJobs(database.GetDB(), campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry campaign"})
+		return
+	}
+
+	if requeued > 0 {
+		recordAudit(c, models.AuditActionSend, "newsletter_retry", campaign.ID, nil, gin.H{
+			"event_id": campaign.EventID,
+			"requeued": requeued,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Failed recipients queued for retry",
+		"requeued": requeued,
+	})
+}
+
+// loadCampaign loads the campaign referenced by the :id path parameter
+func loadCampaign(c *gin.Context) (*models.EmailCampaign, bool) {
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return nil, false
+	}
+
+	var campaign models.EmailCampaign
+	if err := database.GetDB().First(&campaign, campaignID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+
+	return &campaign, true
+}
+
+// parsePagination reads page and limit query parameters (limit capped at 100)
+func parsePagination(c *gin.Context, defaultLimit int) (int, int) {
+	page := 1
+	limit := defaultLimit
+
+	if p := c.Query("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	return page, limit
+}
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"shipshipship/database"
+	"shipshipship/markdown"
+	"shipshipship/middleware"
 	"shipshipship/models"
 	"shipshipship/services"
 	"shipshipship/utils"
@@ -17,123 +21,237 @@ import (
 )
 
 // Helper function to get reaction summary for an event
-func getReactionSummary(db *gorm.DB, eventID uint, clientIP string) models.ReactionSummary {
-	var reactions []models.ReactionCount
+func getReactionSummary(db *gorm.DB, eventID uint, identities []string) models.ReactionSummary {
+	return getReactionSummaries(db, []uint{eventID}, identities)[eventID]
+}
+
+// getReactionSummaries gets the reaction summaries of several events with two grouped
+// queries, so listing events doesn't cost extra queries per event
+func getReactionSummaries(db *gorm.DB, eventIDs []uint, identities []string) map[uint]models.ReactionSummary {
+	summaries := make(map[uint]models.ReactionSummary, len(eventIDs))
+	for _, eventID := range eventIDs {
+		summaries[eventID] = models.ReactionSummary{
+			EventID:       eventID,
+			Reactions:     []models.ReactionCount{},
+			UserReactions: []models.ReactionType{},
+		}
+	}
+	if len(eventIDs) == 0 {
+		return summaries
+	}
 
-	// Get count for each reaction type
+	// Get count for each event and reaction type
+	var counts []struct {
+		EventID      uint
+		ReactionType models.ReactionType
+		Count        int64
+	}
 	db.Model(&models.EventReaction{}).
-		Select("reaction_type, COUNT(*) as count").
-		Where("event_id = ?", eventID).
-		Group("reaction_type").
-		Scan(&reactions)
+		Select("event_id, reaction_type, COUNT(*) as count").
+		Where("event_id IN ?", eventIDs).
+		Group("event_id, reaction_type").
+		Order("event_id, reaction_type").
+		Scan(&counts)
 
-	// Calculate total count
-	var totalCount int64
-	for _, r := range reactions {
-		totalCount += r.Count
+	for _, count := range counts {
+		summary := summaries[count.EventID]
+		summary.TotalCount += count.Count
+		summary.Reactions = append(summary.Reactions, models.ReactionCount{ReactionType: count.ReactionType, Count: count.Count})
+		summaries[count.EventID] = summary
 	}
 
-	// Get user's reactions
+	// Get user's reactions, which may be stored under both of their identities
 	var userReactions []models.EventReaction
-	db.Where("event_id = ? AND ip_address = ?", eventID, clientIP).Find(&userReactions)
-
-	userReactionTypes := make([]models.ReactionType, len(userReactions))
-	for i, r := range userReactions {
-		userReactionTypes[i] = r.ReactionType
+	db.Select("event_id, reaction_type").
+		Where("event_id IN ? AND identity IN ?", eventIDs, identities).
+		Order("id").
+		Find(&userReactions)
+
+	type userReaction struct {
+		eventID      uint
+		reactionType models.ReactionType
+	}
+	seen := map[userReaction]bool{}
+	for _, reaction := range userReactions {
+		key := userReaction{reaction.EventID, reaction.ReactionType}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		summary := summaries[reaction.EventID]
+		summary.UserReactions = append(summary.UserReactions, reaction.ReactionType)
+		summaries[reaction.EventID] = summary
 	}
 
-	return models.ReactionSummary{
-		EventID:       eventID,
-		TotalCount:    totalCount,
-		Reactions:     reactions,
-		UserReactions: userReactionTypes,
-	}
+	return summaries
 }
 
+// EventWithReactions is an event in a list response with its reaction summary
+type EventWithReactions struct {
+	models.Event
+	ReactionSummary models.ReactionSummary `json:"reaction_summary"`
+}
+
+// GetEvents returns public events, oldest first. Without query parameters it returns
+// all of them as an array; see listEvents for the paginated form.
 func GetEvents(c *gin.Context) {
-	var events []models.Event
+	listEvents(c, true)
+}
+
+// GetAllEvents returns all events for the admin, newest first. Without query
+// parameters it returns all of them as an array; see listEvents for the paginated form.
+func GetAllEvents(c *gin.Context) {
+	listEvents(c, false)
+}
+
+// Query parameters that switch event lists to the paginated response
+var eventListParams = []string{"limit", "cursor", "sort", "order", "status", "tag_ids", "from", "to", "is_public"}
+
+// listEvents handles the event list endpoints. When any of eventListParams is set the
+// response is a page of events with metadata: limit (default 20, max 100), cursor
+// (next_cursor of the previous page), sort (created_at, date or reactions), order
+// (asc or desc), status (comma-separated names), tag_ids (comma-separated, any
+// match), from/to on the creation date and, for admins, is_public.
+func listEvents(c *gin.Context, publicOnly bool) {
+	defaultOrder := "desc"
+	if publicOnly {
+		defaultOrder = "asc"
+	}
 
 	db := database.GetDB()
 
-	if err := db.Preload("Tags").Where("is_public = ?", true).Order("created_at ASC").Find(&events).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+	paginated := false
+	for _, name := range eventListParams {
+		if _, ok := c.GetQuery(name); ok {
+			paginated = true
+			break
+		}
+	}
+	if !paginated {
+		var events []models.Event
+		query := db.Preload("Tags").Order("created_at " + strings.ToUpper(defaultOrder))
+		if publicOnly {
+			query = query.Where("is_public = ?", true)
+		}
+		if err := query.Find(&events).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+			return
+		}
+		c.JSON(http.StatusOK, withReactionSummaries(db, events, middleware.VisitorIdentities(c)))
 		return
 	}
 
-	// Get client IP for user-specific reaction data
-	clientIP := c.ClientIP()
+	_, limit := parsePagination(c, 20)
+	params := models.EventListParams{
+		Statuses: splitQueryList(c.Query("status")),
+		Sort:     c.DefaultQuery("sort", models.EventSortCreatedAt),
+		Cursor:   c.Query("cursor"),
+		Limit:    limit,
+	}
+	if !models.IsEventSortKey(params.Sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, expected created_at, date or reactions"})
+		return
+	}
 
-	// Build response with reaction summaries
-	type EventWithReactions struct {
-		models.Event
-		ReactionSummary models.ReactionSummary `json:"reaction_summary"`
+	switch order := strings.ToLower(c.DefaultQuery("order", defaultOrder)); order {
+	case "asc", "desc":
+		params.Descending = order == "desc"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order, expected asc or desc"})
+		return
 	}
 
-	eventsWithReactions := make([]EventWithReactions, len(events))
-	for i, event := range events {
-		// Sanitize media URLs to convert localhost to relative URLs
-		var mediaURLs []string
-		if event.Media != "" {
-			json.Unmarshal([]byte(event.Media), &mediaURLs)
-			sanitizedURLs := SanitizeImageURLs(mediaURLs)
-			sanitizedJSON, _ := json.Marshal(sanitizedURLs)
-			event.Media = string(sanitizedJSON)
+	for _, value := range splitQueryList(c.Query("tag_ids")) {
+		tagID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag IDs"})
+			return
 		}
+		params.TagIDs = append(params.TagIDs, uint(tagID))
+	}
 
-		// Sanitize content URLs (HTML content with image tags)
-		event.Content = SanitizeHTMLContent(event.Content)
+	var ok bool
+	if params.From, ok = parseSearchDate(c.Query("from"), false); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	if params.To, ok = parseSearchDate(c.Query("to"), true); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+		return
+	}
 
-		summary := getReactionSummary(db, event.ID, clientIP)
-		eventsWithReactions[i] = EventWithReactions{
-			Event:           event,
-			ReactionSummary: summary,
+	if publicOnly {
+		isPublic := true
+		params.IsPublic = &isPublic
+	} else if value := c.Query("is_public"); value != "" {
+		isPublic, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid is_public value"})
+			return
 		}
+		params.IsPublic = &isPublic
 	}
 
-	c.JSON(http.StatusOK, eventsWithReactions)
-}
-
-func GetAllEvents(c *gin.Context) {
-	var events []models.Event
-
-	db := database.GetDB()
-
-	if err := db.Preload("Tags").Order("created_at DESC").Find(&events).Error; err != nil {
+	page, err := models.ListEvents(db, params)
+	if errors.Is(err, models.ErrInvalidEventCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
 		return
 	}
 
-	// Get client IP for user-specific reaction data
-	clientIP := c.ClientIP()
+	order := "asc"
+	if params.Descending {
+		order = "desc"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"events":      withReactionSummaries(db, page.Events, middleware.VisitorIdentities(c)),
+		"total":       page.Total,
+		"limit":       limit,
+		"sort":        params.Sort,
+		"order":       order,
+		"has_more":    page.HasMore,
+		"next_cursor": page.NextCursor,
+	})
+}
 
-	// Build response with reaction summaries
-	type EventWithReactions struct {
-		models.Event
-		ReactionSummary models.ReactionSummary `json:"reaction_summary"`
+// prepareEventResponse turns localhost media and image URLs into relative ones,
+// sanitizes the content and adds it rendered as HTML
+func prepareEventResponse(event *models.Event) {
+	var mediaURLs []string
+	if event.Media != "" {
+		json.Unmarshal([]byte(event.Media), &mediaURLs)
+		sanitizedURLs := SanitizeImageURLs(mediaURLs)
+		sanitizedJSON, _ := json.Marshal(sanitizedURLs)
+		event.Media = string(sanitizedJSON)
 	}
 
-	eventsWithReactions := make([]EventWithReactions, len(events))
+	// Sanitize content URLs (HTML content with image tags). Content is sanitized on
+	// write too, this covers events stored before that.
+	event.Content = SanitizeHTMLContent(markdown.Sanitize(event.Content))
+	event.ContentHTML = SanitizeHTMLContent(markdown.ToHTML(event.Content))
+}
+
+// withReactionSummaries sanitizes event URLs and adds the reaction summaries
+func withReactionSummaries(db *gorm.DB, events []models.Event, identities []string) []EventWithReactions {
+	eventIDs := make([]uint, len(events))
 	for i, event := range events {
-		// Sanitize media URLs to convert localhost to relative URLs
-		var mediaURLs []string
-		if event.Media != "" {
-			json.Unmarshal([]byte(event.Media), &mediaURLs)
-			sanitizedURLs := SanitizeImageURLs(mediaURLs)
-			sanitizedJSON, _ := json.Marshal(sanitizedURLs)
-			event.Media = string(sanitizedJSON)
-		}
+		eventIDs[i] = event.ID
+	}
+	summaries := getReactionSummaries(db, eventIDs, identities)
 
-		// Sanitize content URLs (HTML content with image tags)
-		event.Content = SanitizeHTMLContent(event.Content)
+	eventsWithReactions := make([]EventWithReactions, len(events))
+	for i, event := range events {
+		prepareEventResponse(&event)
 
-		summary := getReactionSummary(db, event.ID, clientIP)
 		eventsWithReactions[i] = EventWithReactions{
 			Event:           event,
-			ReactionSummary: summary,
+			ReactionSummary: summaries[event.ID],
 		}
 	}
-
-	c.JSON(http.StatusOK, eventsWithReactions)
+	return eventsWithReactions
 }
 
 func GetEvent(c *gin.Context) {
@@ -153,28 +271,10 @@ func GetEvent(c *gin.Context) {
 		return
 	}
 
-	// Sanitize media URLs to convert localhost to relative URLs
-	var mediaURLs []string
-	if event.Media != "" {
-		json.Unmarshal([]byte(event.Media), &mediaURLs)
-		sanitizedURLs := SanitizeImageURLs(mediaURLs)
-		sanitizedJSON, _ := json.Marshal(sanitizedURLs)
-		event.Media = string(sanitizedJSON)
-	}
-
-	// Sanitize content URLs (HTML content with image tags)
-	event.Content = SanitizeHTMLContent(event.Content)
-
-	// Get client IP for user-specific reaction data
-	clientIP := c.ClientIP()
-
-	// Build response with reaction summary
-	type EventWithReactions struct {
-		models.Event
-		ReactionSummary models.ReactionSummary `json:"reaction_summary"`
-	}
+	prepareEventResponse(&event)
 
-	summary := getReactionSummary(db, event.ID, clientIP)
+	// Build response with the visitor's own reactions
+	summary := getReactionSummary(db, event.ID, middleware.VisitorIdentities(c))
 	response := EventWithReactions{
 		Event:           event,
 		ReactionSummary: summary,
@@ -204,28 +304,10 @@ func GetEventBySlug(c *gin.Context) {
 		return
 	}
 
-	// Sanitize media URLs to convert localhost to relative URLs
-	var mediaURLs []string
-	if event.Media != "" {
-		json.Unmarshal([]byte(event.Media), &mediaURLs)
-		sanitizedURLs := SanitizeImageURLs(mediaURLs)
-		sanitizedJSON, _ := json.Marshal(sanitizedURLs)
-		event.Media = string(sanitizedJSON)
-	}
-
-	// Sanitize content URLs (HTML content with image tags)
-	event.Content = SanitizeHTMLContent(event.Content)
-
-	// Get client IP for user-specific reaction data
-	clientIP := c.ClientIP()
-
-	// Build response with reaction summary
-	type EventWithReactions struct {
-		models.Event
-		ReactionSummary models.ReactionSummary `json:"reaction_summary"`
-	}
+	prepareEventResponse(&event)
 
-	summary := getReactionSummary(db, event.ID, clientIP)
+	// Build response with the visitor's own reactions
+	summary := getReactionSummary(db, event.ID, middleware.VisitorIdentities(c))
 	response := EventWithReactions{
 		Event:           event,
 		ReactionSummary: summary,
@@ -265,7 +347,7 @@ func CreateEvent(c *gin.Context) {
 		Media:   string(mediaJSON),
 		Status:  req.Status,
 		Date:    req.Date,
-		Content: req.Content,
+		Content: markdown.Sanitize(req.Content),
 	}
 
 	if err := db.Create(&event).Error; err != nil {
@@ -292,6 +374,17 @@ func CreateEvent(c *gin.Context) {
 		return
 	}
 
+	if _, err := models.CreateEventRevision(db, &event, c.GetString("username")); err != nil {
+		fmt.Printf("Warning: Failed to create revision for event %d: %v\n", event.ID, err)
+	}
+	if err := services.IndexEvent(db, event.ID); err != nil {
+		fmt.Printf("Warning: Failed to update search index for event %d: %v\n", event.ID, err)
+	}
+
+	recordAudit(c, models.AuditActionCreate, "event", event.ID, nil, event)
+	services.DispatchWebhookEvent(models.WebhookEventCreated, gin.H{"event": event})
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusCreated, event)
 }
 
@@ -309,6 +402,12 @@ func UpdateEvent(c *gin.Context) {
 		return
 	}
 
+	applyEventUpdate(c, eventID, req)
+}
+
+// applyEventUpdate applies an update request to an event and writes the response.
+// Shared by UpdateEvent and RestoreEventRevision so restores behave like normal edits.
+func applyEventUpdate(c *gin.Context, eventID uint64, req models.UpdateEventRequest) {
 	db := database.GetDB()
 	var event models.Event
 	if err := db.Preload("Tags").First(&event, eventID).Error; err != nil {
@@ -316,8 +415,14 @@ func UpdateEvent(c *gin.Context) {
 		return
 	}
 
+	// Events created before revision history existed get a baseline snapshot first
+	if err := models.EnsureEventRevision(db, &event); err != nil {
+		fmt.Printf("Warning: Failed to create baseline revision for event %d: %v\n", event.ID, err)
+	}
+
 	// Store the original status to detect changes
 	originalStatus := event.Status
+	before := event
 
 	// Update fields if provided
 	if req.Title != nil {
@@ -344,37 +449,10 @@ func UpdateEvent(c *gin.Context) {
 			return
 		}
 	}
+	// Media and content images removed by an edit stay on disk because earlier
+	// revisions may still reference them; the cleanup service deletes files
+	// once no event or revision uses them anymore.
 	if req.Media != nil {
-		// Clean up old media files that are no longer referenced
-		if event.Media != "" {
-			// Parse old media URLs
-			var oldMediaURLs []string
-			if err := json.Unmarshal([]byte(event.Media), &oldMediaURLs); err == nil {
-				// Find URLs that are in old media but not in new media
-				newMediaURLs := req.Media
-				removedURLs := []string{}
-
-				for _, oldURL := range oldMediaURLs {
-					found := false
-					for _, newURL := range newMediaURLs {
-						if oldURL == newURL {
-							found = true
-							break
-						}
-					}
-					if !found {
-						removedURLs = append(removedURLs, oldURL)
-					}
-				}
-
-				// Clean up only the removed URLs
-				for _, removedURL := range removedURLs {
-					if err := deleteImageFromURL(removedURL); err != nil {
-						fmt.Printf("Warning: Failed to cleanup removed media file %s for event %d: %v\n", removedURL, eventID, err)
-					}
-				}
-			}
-		}
 		mediaJSON, _ := json.Marshal(req.Media)
 		event.Media = string(mediaJSON)
 	}
@@ -390,28 +468,7 @@ func UpdateEvent(c *gin.Context) {
 		event.Date = *req.Date
 	}
 	if req.Content != nil {
-		// Clean up images that were removed from content
-		if event.Content != "" && event.Content != *req.Content {
-			oldImages := extractImagesFromContent(event.Content)
-			newImages := extractImagesFromContent(*req.Content)
-
-			// Find images that are in old content but not in new content
-			for _, oldURL := range oldImages {
-				found := false
-				for _, newURL := range newImages {
-					if oldURL == newURL {
-						found = true
-						break
-					}
-				}
-				if !found {
-					if err := deleteImageFromURL(oldURL); err != nil {
-						fmt.Printf("Warning: Failed to cleanup removed content image %s for event %d: %v\n", oldURL, eventID, err)
-					}
-				}
-			}
-		}
-		event.Content = *req.Content
+		event.Content = markdown.Sanitize(*req.Content)
 	}
 	// Order field removed
 
@@ -436,6 +493,23 @@ func UpdateEvent(c *gin.Context) {
 		return
 	}
 
+	if _, err := models.CreateEventRevision(db, &event, c.GetString("username")); err != nil {
+		fmt.Printf("Warning: Failed to create revision for event %d: %v\n", event.ID, err)
+	}
+	if err := services.IndexEvent(db, event.ID); err != nil {
+		fmt.Printf("Warning: Failed to update search index for event %d: %v\n", event.ID, err)
+	}
+
+	recordAudit(c, models.AuditActionUpdate, "event", event.ID, before, event)
+
+	if originalStatus != event.Status {
+		services.DispatchWebhookEvent(models.WebhookEventStatusChanged, gin.H{
+			"event":           event,
+			"previous_status": originalStatus,
+		})
+	}
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, event)
 }
 
@@ -451,7 +525,7 @@ func DeleteEvent(c *gin.Context) {
 
 	// First, get the event to access its media files before deletion
 	var event models.Event
-	if err := db.First(&event, eventID).Error; err != nil {
+	if err := db.Preload("Tags").First(&event, eventID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
@@ -478,6 +552,17 @@ func DeleteEvent(c *gin.Context) {
 		return
 	}
 
+	// Revisions reference the media deleted above, so they go with the event
+	if err := db.Where("event_id = ?", eventID).Delete(&models.EventRevision{}).Error; err != nil {
+		fmt.Printf("Warning: Failed to delete revisions for event %d: %v\n", eventID, err)
+	}
+	if err := services.RemoveEventFromIndex(db, event.ID); err != nil {
+		fmt.Printf("Warning: Failed to remove event %d from search index: %v\n", eventID, err)
+	}
+
+	recordAudit(c, models.AuditActionDelete, "event", event.ID, event, nil)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{"message": "Event deleted successfully"})
 }
 
@@ -489,9 +574,6 @@ func VoteEvent(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 	var event models.Event
 	if err := db.First(&event, eventID).Error; err != nil {
@@ -499,30 +581,34 @@ func VoteEvent(c *gin.Context) {
 		return
 	}
 
+	identity := voterIdentity(c, db, event.ID)
+
 	// Voting now allowed for all statuses (restriction removed)
 
-	// Check if this IP has already voted for this event using count to avoid error logging
+	// Check if this visitor has already voted for this event using count to avoid error logging
 	var voteCount int64
-	db.Model(&models.Vote{}).Where("event_id = ? AND ip_address = ?", eventID, clientIP).Count(&voteCount)
+	db.Model(&models.Vote{}).Where("event_id = ? AND identity = ?", eventID, identity).Count(&voteCount)
 	if voteCount > 0 {
 		// User has already voted, get the existing vote for deletion
 		var existingVote models.Vote
-		db.Where("event_id = ? AND ip_address = ?", eventID, clientIP).First(&existingVote)
+		db.Where("event_id = ? AND identity = ?", eventID, identity).First(&existingVote)
 		// User has already voted, so remove the vote (toggle functionality)
-		if err := db.Delete(&existingVote).Error; err != nil {
+		result := db.Delete(&existingVote)
+		if result.Error != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vote"})
 			return
 		}
 
-		// Decrement vote count
-		if event.Votes > 0 {
+		// Decrement vote count, unless a concurrent request removed the vote first
+		if result.RowsAffected > 0 && event.Votes > 0 {
+			if err := db.Model(&event).UpdateColumn("votes", gorm.Expr("MAX(votes - 1, 0)")).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote count"})
+				return
+			}
 			event.Votes--
 		}
-		if err := db.Save(&event).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote count"})
-			return
-		}
 
+		middleware.BumpContentVersion()
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Vote removed successfully",
 			"votes":   event.Votes,
@@ -534,21 +620,32 @@ func VoteEvent(c *gin.Context) {
 	// Create vote record
 	vote := models.Vote{
 		EventID:   uint(eventID),
-		IPAddress: clientIP,
+		IPAddress: middleware.HashIP(c.ClientIP()),
+		Identity:  identity,
 	}
 
 	if err := db.Create(&vote).Error; err != nil {
+		// A concurrent request of the same visitor recorded the vote first
+		if models.IsDuplicateError(err) {
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Vote recorded successfully",
+				"votes":   event.Votes,
+				"voted":   true,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
 		return
 	}
 
 	// Increment vote count
-	event.Votes++
-	if err := db.Save(&event).Error; err != nil {
+	if err := db.Model(&event).UpdateColumn("votes", gorm.Expr("votes + 1")).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote count"})
 		return
 	}
+	event.Votes++
 
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Vote recorded successfully",
 		"votes":   event.Votes,
@@ -564,9 +661,6 @@ func CheckVoteStatus(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 	var event models.Event
 	if err := db.First(&event, eventID).Error; err != nil {
@@ -574,9 +668,9 @@ func CheckVoteStatus(c *gin.Context) {
 		return
 	}
 
-	// Check if this IP has voted for this event using count to avoid error logging
+	// Check if this visitor has voted for this event using count to avoid error logging
 	var voteCount int64
-	db.Model(&models.Vote{}).Where("event_id = ? AND ip_address = ?", eventID, clientIP).Count(&voteCount)
+	db.Model(&models.Vote{}).Where("event_id = ? AND identity IN ?", eventID, middleware.VisitorIdentities(c)).Count(&voteCount)
 	hasVoted := voteCount > 0
 
 	c.JSON(http.StatusOK, gin.H{
@@ -671,7 +765,7 @@ func SubmitFeedback(c *gin.Context) {
 		Media:   string(mediaJSON),
 		Status:  feedbackStatus,
 		Date:    "",
-		Content: req.Content,
+		Content: markdown.Sanitize(req.Content),
 	}
 
 	if err := db.Create(&event).Error; err != nil {
@@ -698,6 +792,14 @@ func SubmitFeedback(c *gin.Context) {
 		fmt.Printf("Warning: Failed to associate feedback tag with event %d: %v\n", event.ID, err)
 	}
 
+	if err := services.IndexEvent(db, event.ID); err != nil {
+		fmt.Printf("Warning: Failed to update search index for event %d: %v\n", event.ID, err)
+	}
+
+	db.Preload("Tags").First(&event, event.ID)
+	services.DispatchWebhookEvent(models.WebhookFeedbackSubmitted, gin.H{"event": event})
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Feedback submitted successfully",
 		"id":      event.ID,
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchEvents runs a full-text search over public events
+func SearchEvents(c *gin.Context) {
+	searchEvents(c, true)
+}
+
+// SearchAllEvents runs a full-text search over all events, including hidden ones
+func SearchAllEvents(c *gin.Context) {
+	searchEvents(c, false)
+}
+
+// searchEvents handles ?q= with optional status and tag filters (comma-separated names)
+// and a from/to range on the event date (YYYY-MM-DD, both inclusive)
+func searchEvents(c *gin.Context, publicOnly bool) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	page, limit := parsePagination(c, 20)
+	params := services.EventSearchParams{
+		Query:      query,
+		Statuses:   splitQueryList(c.Query("status")),
+		Tags:       splitQueryList(c.Query("tag")),
+		PublicOnly: publicOnly,
+		Page:       page,
+		Limit:      limit,
+	}
+
+	var ok bool
+	if params.From, ok = parseEventDate(c.Query("from")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	if params.To, ok = parseEventDate(c.Query("to")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+		return
+	}
+
+	db := database.GetDB()
+	results, total, err := services.SearchEvents(db, params)
+	if errors.Is(err, services.ErrSearchUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Search is not available on this server"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search events"})
+		return
+	}
+
+	for i := range results {
+		prepareEventResponse(&results[i].Event)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      results,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseSearchDate parses a date filter. A plain date as upper bound covers the whole day.
+func parseSearchDate(value string, endOfDay bool) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, true
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, false
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, true
+}
+
+// parseEventDate checks an event date filter and returns it as YYYY-MM-DD, the form event
+// dates start with
+func parseEventDate(value string) (string, bool) {
+	if value == "" {
+		return "", true
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return "", false
+	}
+	return parsed.Format("2006-01-02"), true
+}
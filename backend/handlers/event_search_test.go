@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shipshipship/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func searchRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/events/search", SearchEvents)
+	return router
+}
+
+func TestSearchEventsValidation(t *testing.T) {
+	setupEventsDB(t, 1)
+	router := searchRouter()
+
+	for _, path := range []string{
+		"/api/events/search",
+		"/api/events/search?q=dark&from=yesterday",
+		"/api/events/search?q=dark&to=2024-13-01",
+		"/api/events/search?q=dark&from=2024-01-01T00:00:00Z",
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want %d", path, recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// TestSearchEventsUnavailable checks that search answers 503 when SQLite lacks FTS5
+func TestSearchEventsUnavailable(t *testing.T) {
+	db := setupEventsDB(t, 1)
+	if err := services.SetupEventSearch(db); err == nil {
+		t.Skip("SQLite was built with FTS5")
+	}
+
+	recorder := httptest.NewRecorder()
+	searchRouter().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/events/search?q=dark", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want %d: %s", recorder.Code, http.StatusServiceUnavailable, recorder.Body.String())
+	}
+}
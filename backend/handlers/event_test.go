@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"shipshipship/database"
+	"shipshipship/middleware"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupEventsDB points the handlers at a new database with count events, each with a
+// tag and reactions, and returns it
+func setupEventsDB(t testing.TB, count int) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Event{}, &models.Tag{}, &models.EventReaction{}, &models.IPHashKey{}); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+
+	tag := models.Tag{Name: "feature", Color: "#3b82f6"}
+	if err := db.Create(&tag).Error; err != nil {
+		t.Fatalf("creating tag: %v", err)
+	}
+	for i := 0; i < count; i++ {
+		event := models.Event{
+			Title:    fmt.Sprintf("Event %d", i),
+			Slug:     fmt.Sprintf("event-%d", i),
+			Status:   "Released",
+			Content:  "Some **content**",
+			IsPublic: true,
+			Tags:     []models.Tag{tag},
+		}
+		if err := db.Create(&event).Error; err != nil {
+			t.Fatalf("creating event: %v", err)
+		}
+		for _, reaction := range []models.ReactionType{models.ReactionThumbsUp, models.ReactionHeart} {
+			err := db.Create(&models.EventReaction{
+				EventID:      event.ID,
+				ReactionType: reaction,
+				Identity:     fmt.Sprintf("v:visitor-%d", i),
+			}).Error
+			if err != nil {
+				t.Fatalf("creating reaction: %v", err)
+			}
+		}
+	}
+	return db
+}
+
+// countQueries returns how many statements db runs while fn runs
+func countQueries(t testing.TB, db *gorm.DB, fn func()) int {
+	t.Helper()
+	queries := 0
+	count := func(*gorm.DB) { queries++ }
+	name := "test:count_queries"
+	db.Callback().Query().After("gorm:query").Register(name, count)
+	db.Callback().Row().After("gorm:row").Register(name, count)
+	db.Callback().Raw().After("gorm:raw").Register(name, count)
+	defer func() {
+		db.Callback().Query().Remove(name)
+		db.Callback().Row().Remove(name)
+		db.Callback().Raw().Remove(name)
+	}()
+
+	fn()
+	return queries
+}
+
+// eventsRouter serves the public and admin event lists and the slug lookup
+func eventsRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/events", middleware.Visitor(), GetEvents)
+	router.GET("/api/events/slug/:slug", middleware.Visitor(), GetEventBySlug)
+	router.GET("/api/admin/events", GetAllEvents)
+	return router
+}
+
+// serveEvents runs a GET request and fails unless it succeeds
+func serveEvents(t testing.TB, router *gin.Engine, path string) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", path, recorder.Code, recorder.Body.String())
+	}
+}
+
+// TestListEventsQueryCount checks that listing events and looking one up cost the same
+// number of queries however many events there are, so reaction summaries are not
+// fetched per event
+func TestListEventsQueryCount(t *testing.T) {
+	router := eventsRouter()
+
+	for _, path := range []string{"/api/events", "/api/events?limit=100", "/api/admin/events", "/api/events/slug/event-5"} {
+		t.Run(path, func(t *testing.T) {
+			counts := map[int]int{}
+			for _, events := range []int{10, 100} {
+				db := setupEventsDB(t, events)
+
+				// The first request also loads the IP hash key, which is then cached
+				serveEvents(t, router, path)
+				counts[events] = countQueries(t, db, func() { serveEvents(t, router, path) })
+			}
+
+			t.Logf("queries: %d for 10 events, %d for 100 events", counts[10], counts[100])
+			if counts[10] != counts[100] {
+				t.Errorf("queries for 10 events = %d, for 100 events = %d, want equal", counts[10], counts[100])
+			}
+		})
+	}
+}
+
+// BenchmarkListEvents lists all public events and reports the queries per request
+func BenchmarkListEvents(b *testing.B) {
+	router := eventsRouter()
+
+	for _, events := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("%d events", events), func(b *testing.B) {
+			db := setupEventsDB(b, events)
+			serveEvents(b, router, "/api/events")
+
+			b.ResetTimer()
+			queries := countQueries(b, db, func() {
+				for i := 0; i < b.N; i++ {
+					serveEvents(b, router, "/api/events")
+				}
+			})
+			b.ReportMetric(float64(queries)/float64(b.N), "queries/op")
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/markdown"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Maximum number of events included in a feed
+const feedItemLimit = 50
+
+// relativeUploadPattern matches src/href attributes pointing at uploaded files
+var relativeUploadPattern = regexp.MustCompile(`(src|href)=(["'])(/api/uploads/[^"']*)(["'])`)
+
+// rssFeed is the root element of an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed is the root element of an Atom document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       *atomLink      `xml:"link,omitempty"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// feedData holds everything needed to render either feed format
+type feedData struct {
+	Title   string
+	BaseURL string
+	SelfURL string
+	Events  []models.Event
+}
+
+// GetRSSFeed serves public events as an RSS 2.0 feed (?category= or ?tag= to filter)
+func GetRSSFeed(c *gin.Context) {
+	data, ok := loadFeedData(c)
+	if !ok {
+		return
+	}
+
+	channel := rssChannel{
+		Title:       data.Title,
+		Link:        data.BaseURL + "/",
+		Description: fmt.Sprintf("Latest updates from %s", data.Title),
+		SelfLink:    atomLink{Href: data.SelfURL, Rel: "self", Type: "application/rss+xml"},
+		Items:       []rssItem{},
+	}
+	if len(data.Events) > 0 {
+		channel.LastBuildDate = latestUpdate(data.Events).Format(time.RFC1123Z)
+	}
+
+	for _, event := range data.Events {
+		link, permalink := feedEventLink(data.BaseURL, event)
+		guid := rssGUID{IsPermaLink: permalink, Value: link}
+		if !permalink {
+			guid.Value = feedEventID(data.BaseURL, event)
+		}
+
+		channel.Items = append(channel.Items, rssItem{
+			Title:       event.Title,
+			Link:        link,
+			GUID:        guid,
+			PubDate:     event.CreatedAt.UTC().Format(time.RFC1123Z),
+			Categories:  feedEventCategories(event),
+			Description: feedEventContent(data.BaseURL, event),
+		})
+	}
+
+	writeFeed(c, "application/rss+xml; charset=utf-8", rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: channel,
+	})
+}
+
+// GetAtomFeed serves public events as an Atom feed (?category= or ?tag= to filter)
+func GetAtomFeed(c *gin.Context) {
+	data, ok := loadFeedData(c)
+	if !ok {
+		return
+	}
+
+	updated := time.Now().UTC()
+	if len(data.Events) > 0 {
+		updated = latestUpdate(data.Events)
+	}
+
+	feed := atomFeed{
+		Title:   data.Title,
+		ID:      data.SelfURL,
+		Updated: updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: data.SelfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: data.BaseURL + "/", Rel: "alternate", Type: "text/html"},
+		},
+		Entries: []atomEntry{},
+	}
+
+	for _, event := range data.Events {
+		entry := atomEntry{
+			Title:     event.Title,
+			ID:        feedEventID(data.BaseURL, event),
+			Published: event.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   event.UpdatedAt.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Value: feedEventContent(data.BaseURL, event)},
+		}
+		if link, permalink := feedEventLink(data.BaseURL, event); permalink {
+			entry.Link = &atomLink{Href: link, Rel: "alternate", Type: "text/html"}
+		}
+		for _, category := range feedEventCategories(event) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	writeFeed(c, "application/atom+xml; charset=utf-8", feed)
+}
+
+// loadFeedData loads the public events for a feed, applying the category and tag filters
+func loadFeedData(c *gin.Context) (*feedData, bool) {
+	db := database.GetDB()
+
+	settings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return nil, false
+	}
+
+	baseURL := strings.TrimSuffix(getBaseURL(c, db), "/")
+
+	query := db.Preload("Tags").Where("events.is_public = ?", true)
+	title := settings.Title
+
+	if categoryID := c.Query("category"); categoryID != "" {
+		category := findThemeCategory(categoryID)
+		if category == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return nil, false
+		}
+		statuses, err := statusesForCategory(db, settings.CurrentThemeID, category.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status mappings"})
+			return nil, false
+		}
+		query = query.Where("events.status IN ?", statuses)
+		label := category.Label
+		if label == "" {
+			label = category.ID
+		}
+		title = fmt.Sprintf("%s - %s", title, label)
+	}
+
+	if tagName := c.Query("tag"); tagName != "" {
+		var tag models.Tag
+		if err := db.Where("LOWER(name) = ?", strings.ToLower(tagName)).First(&tag).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+			return nil, false
+		}
+		query = query.Where("events.id IN (?)", db.Table("event_tags").Select("event_id").Where("tag_id = ?", tag.ID))
+		title = fmt.Sprintf("%s - %s", title, tag.Name)
+	}
+
+	var events []models.Event
+	if err := query.Order("events.created_at DESC").Limit(feedItemLimit).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return nil, false
+	}
+
+	return &feedData{
+		Title:   title,
+		BaseURL: baseURL,
+		SelfURL: baseURL + c.Request.URL.RequestURI(),
+		Events:  events,
+	}, true
+}
+
+// findThemeCategory returns a category of the installed theme by ID
+func findThemeCategory(categoryID string) *models.ThemeCategory {
+	for _, category := range currentThemeCategories() {
+		if category.ID == categoryID {
+			return &category
+		}
+	}
+	return nil
+}
+
+// statusesForCategory returns the status names mapped to a theme category
+func statusesForCategory(db *gorm.DB, themeID, categoryID string) ([]string, error) {
+	statuses := []string{}
+	err := db.Model(&models.EventStatusDefinition{}).
+		Joins("JOIN status_category_mappings ON status_category_mappings.status_definition_id = event_status_definitions.id").
+		Where("status_category_mappings.theme_id = ? AND status_category_mappings.category_id = ?", themeID, categoryID).
+		Pluck("event_status_definitions.display_name", &statuses).Error
+	return statuses, err
+}
+
+// feedEventLink returns the public URL of an event and whether it is a permalink.
+// Events without a public URL link to the changelog page instead.
+func feedEventLink(baseURL string, event models.Event) (string, bool) {
+	if event.HasPublicUrl && event.Slug != "" {
+		return fmt.Sprintf("%s/%s", baseURL, event.Slug), true
+	}
+	return baseURL + "/", false
+}
+
+// feedEventID returns a stable identifier for an event that survives slug changes
+func feedEventID(baseURL string, event models.Event) string {
+	return fmt.Sprintf("%s/#event-%d", baseURL, event.ID)
+}
+
+// feedEventCategories returns the status and tag names of an event
+func feedEventCategories(event models.Event) []string {
+	categories := []string{string(event.Status)}
+	for _, tag := range event.Tags {
+		categories = append(categories, tag.Name)
+	}
+	return categories
+}
+
+// feedEventContent returns the event content as HTML with absolute upload URLs
+func feedEventContent(baseURL string, event models.Event) string {
+	content := SanitizeHTMLContent(markdown.ToHTML(event.Content))
+	return relativeUploadPattern.ReplaceAllString(content, fmt.Sprintf("${1}=${2}%s${3}${4}", baseURL))
+}
+
+// latestUpdate returns the most recent update time across events
+func latestUpdate(events []models.Event) time.Time {
+	var latest time.Time
+	for _, event := range events {
+		if event.UpdatedAt.After(latest) {
+			latest = event.UpdatedAt
+		}
+	}
+	return latest.UTC()
+}
+
+// writeFeed encodes a feed document with the XML header
+func writeFeed(c *gin.Context, contentType string, feed interface{}) {
+	output, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate feed"})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), output...))
+}
@@ -3,14 +3,15 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"net/smtp"
-	"strings"
+	"net/url"
 
 	"shipshipship/database"
+	"shipshipship/email"
 	"shipshipship/models"
-	"shipshipship/utils"
+	"shipshipship/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func GetMailSettings(c *gin.Context) {
@@ -21,10 +22,21 @@ func GetMailSettings(c *gin.Context) {
 		return
 	}
 
-	// Don't return the password in the response for security
-	settings.SMTPPassword = ""
+	// Bounce webhooks are rejected until a secret exists, so create one on first view
+	if settings.BounceWebhookSecret == "" {
+		secret, err := services.GenerateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate bounce webhook secret"})
+			return
+		}
+		settings.BounceWebhookSecret = secret
+		if err := db.Model(settings).Update("bounce_webhook_secret", secret).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save bounce webhook secret"})
+			return
+		}
+	}
 
-	c.JSON(http.StatusOK, settings)
+	c.JSON(http.StatusOK, mailSettingsResponse(c, db, settings))
 }
 
 func UpdateMailSettings(c *gin.Context) {
@@ -41,6 +53,8 @@ func UpdateMailSettings(c *gin.Context) {
 		return
 	}
 
+	before := *settings
+
 	// Update fields if provided
 	if req.SMTPHost != nil {
 		settings.SMTPHost = *req.SMTPHost
@@ -63,16 +77,112 @@ func UpdateMailSettings(c *gin.Context) {
 	if req.FromName != nil {
 		settings.FromName = *req.FromName
 	}
+	if req.Provider != nil {
+		if !email.IsValidProvider(*req.Provider) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown mail provider: %s", *req.Provider)})
+			return
+		}
+		settings.Provider = *req.Provider
+	}
+	// Empty or masked credentials (as returned by GetMailSettings) keep the stored value
+	if req.APIKey != nil && *req.APIKey != "" && *req.APIKey != maskSecret(settings.APIKey) {
+		settings.APIKey = *req.APIKey
+	}
+	if req.APISecret != nil && *req.APISecret != "" {
+		settings.APISecret = *req.APISecret
+	}
+	if req.APIDomain != nil {
+		settings.APIDomain = *req.APIDomain
+	}
+	if req.APIRegion != nil {
+		settings.APIRegion = *req.APIRegion
+	}
+	if req.APIEndpoint != nil {
+		settings.APIEndpoint = *req.APIEndpoint
+	}
+	if req.FilePath != nil {
+		settings.FilePath = *req.FilePath
+	}
+	if req.TrackingEnabled != nil {
+		settings.TrackingEnabled = *req.TrackingEnabled
+	}
+	if req.RotateBounceWebhookSecret {
+		secret, err := services.GenerateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate bounce webhook secret"})
+			return
+		}
+		settings.BounceWebhookSecret = secret
+	}
 
 	if err := db.Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mail settings"})
 		return
 	}
 
-	// Don't return the password in the response for security
+	// Never store the password or API credentials in the audit log, only whether they changed
+	after := *settings
+	apiKeyChanged := after.APIKey != before.APIKey
+	apiSecretChanged := after.APISecret != before.APISecret
+	for _, s := range []*models.MailSettings{&before, &after} {
+		s.SMTPPassword = maskSecret(s.SMTPPassword)
+		s.APIKey = maskSecret(s.APIKey)
+		s.APISecret = maskSecret(s.APISecret)
+		s.BounceWebhookSecret = maskSecret(s.BounceWebhookSecret)
+	}
+	if req.SMTPPassword != nil && *req.SMTPPassword != "" {
+		after.SMTPPassword = "******** (changed)"
+	}
+	if apiKeyChanged {
+		after.APIKey = "******** (changed)"
+	}
+	if apiSecretChanged {
+		after.APISecret = "******** (changed)"
+	}
+	if req.RotateBounceWebhookSecret {
+		after.BounceWebhookSecret = "******** (changed)"
+	}
+	recordAudit(c, models.AuditActionUpdate, "mail_settings", settings.ID, before, after)
+
+	c.JSON(http.StatusOK, mailSettingsResponse(c, db, settings))
+}
+
+// mailSettingsResponse hides the password and API credentials and adds the URL
+// bounce and complaint webhooks of the configured provider are sent to
+func mailSettingsResponse(c *gin.Context, db *gorm.DB, settings *models.MailSettings) interface{} {
 	settings.SMTPPassword = ""
+	settings.APIKey = maskSecret(settings.APIKey)
+	settings.APISecret = ""
+
+	source := settings.Provider
+	if !email.IsBounceWebhookSource(source) {
+		source = email.BounceSourceGeneric
+	}
+	// The secret goes in the URL as basic auth credentials, which providers send in the
+	// Authorization header rather than the request line
+	webhookURL := ""
+	if settings.BounceWebhookSecret != "" {
+		if parsed, err := url.Parse(fmt.Sprintf("%s/api/newsletter/bounces/%s", getBaseURL(c, db), source)); err == nil {
+			parsed.User = url.UserPassword(bounceWebhookUser, settings.BounceWebhookSecret)
+			webhookURL = parsed.String()
+		}
+	}
 
-	c.JSON(http.StatusOK, settings)
+	return struct {
+		*models.MailSettings
+		BounceWebhookURL string `json:"bounce_webhook_url"`
+	}{settings, webhookURL}
+}
+
+// Username in the bounce webhook URL; only the password is checked
+const bounceWebhookUser = "bounces"
+
+// maskSecret hides a secret value while keeping whether it is set
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
 }
 
 func TestMailSettings(c *gin.Context) {
@@ -92,14 +202,25 @@ func TestMailSettings(c *gin.Context) {
 		return
 	}
 
+	if suppressed, err := models.IsEmailSuppressed(db, req.Email); err == nil && suppressed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This address is on the suppression list"})
+		return
+	}
+
 	// Validate that required settings are configured
-	if settings.SMTPHost == "" || settings.FromEmail == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "SMTP host and from email must be configured"})
+	if settings.FromEmail == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "From email must be configured"})
+		return
+	}
+
+	transport, err := email.NewTransport(settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Send test email
-	err = sendTestEmail(settings, req.Email)
+	err = sendTestEmail(transport, settings, req.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to send test email: %v", err)})
 		return
@@ -108,48 +229,29 @@ func TestMailSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Test email sent successfully"})
 }
 
-func sendTestEmail(settings *models.MailSettings, toEmail string) error {
+func sendTestEmail(transport email.Transport, settings *models.MailSettings, toEmail string) error {
 	// Prepare email content
 	fromName := settings.FromName
 	if fromName == "" {
 		fromName = "ShipShipShip"
 	}
 
-	from := fmt.Sprintf("%s <%s>", fromName, settings.FromEmail)
-	to := toEmail
-	subject := "ShipShipShip Test Email"
-	body := `This is a test email from ShipShipShip to verify your SMTP configuration.
+	body := `This is a test email from ShipShipShip to verify your mail configuration.
 
 If you received this email, your mail settings are working correctly!
 
 Best regards,
 ShipShipShip Team`
 
-	// Prepare message
-	message := fmt.Sprintf("From: %s\r\n", from)
-	message += fmt.Sprintf("To: %s\r\n", to)
-	message += fmt.Sprintf("Subject: %s\r\n", subject)
-	message += "MIME-Version: 1.0\r\n"
-	message += "Content-Type: text/plain; charset=UTF-8\r\n"
-	message += "\r\n"
-	message += body
-
-	// Determine authentication
-	var auth smtp.Auth
-	if settings.SMTPUsername != "" {
-		auth = smtp.PlainAuth("", settings.SMTPUsername, settings.SMTPPassword, settings.SMTPHost)
-	}
-
-	// Send email based on encryption type
-	addr := fmt.Sprintf("%s:%d", settings.SMTPHost, settings.SMTPPort)
-
-	switch strings.ToLower(settings.SMTPEncryption) {
-	case "ssl":
-		return utils.SendMailWithSSL(addr, auth, settings.FromEmail, []string{toEmail}, []byte(message))
-	case "tls":
-		return utils.SendMailWithTLS(addr, auth, settings.FromEmail, []string{toEmail}, []byte(message))
-	default:
-		// No encryption
-		return smtp.SendMail(addr, auth, settings.FromEmail, []string{toEmail}, []byte(message))
+	msg := &email.Message{
+		FromName:  fromName,
+		FromEmail: settings.FromEmail,
+		To:        toEmail,
+		Subject:   "ShipShipShip Test Email",
+		Text:      body,
+	}
+	if err := msg.Prepare(); err != nil {
+		return err
 	}
+	return transport.Send(msg)
 }
@@ -3,21 +3,30 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
-	"net/smtp"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"shipshipship/constants"
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
-	"shipshipship/utils"
+	"shipshipship/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// How long a newsletter confirmation link stays valid
+const confirmationTokenTTL = 48 * time.Hour
+
+// How long before a pending subscriber can be sent another confirmation email
+const confirmationResendCooldown = 10 * time.Minute
+
 // getBaseURL returns the base URL for the application
 // Priority: 1) BASE_URL env var, 2) constructed from request, 3) relative URL
 func getBaseURL(c *gin.Context, db *gorm.DB) string {
@@ -54,7 +63,7 @@ func SubscribeToNewsletter(c *gin.Context) {
 
 	// Check if user is already subscribed
 	existingSubscriber, err := models.FindSubscriberByEmail(db, req.Email)
-	if err == nil {
+	if err == nil && existingSubscriber.IsConfirmed() {
 		c.JSON(http.StatusOK, gin.H{
 			"message":            "You are already subscribed to our newsletter",
 			"email":              existingSubscriber.Email,
@@ -63,36 +72,132 @@ func SubscribeToNewsletter(c *gin.Context) {
 		return
 	}
 
-	subscriber, err := models.Subscribe(db, req.Email)
+	// Pending subscribers asking again just get a fresh confirmation email
+	subscriber := existingSubscriber
+	if err != nil {
+		subscriber, err = models.Subscribe(db, req.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to newsletter"})
+			return
+		}
+	}
+
+	// Send confirmation email (don't fail subscription if email fails). Repeat requests
+	// within the cooldown get the same answer without another email.
+	send, err := models.ClaimConfirmationEmail(db, subscriber, confirmationResendCooldown)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to newsletter"})
 		return
 	}
-
-	// Send welcome email (don't fail subscription if email fails)
-	go func() {
-		if err := sendWelcomeEmail(db, subscriber.Email); err != nil {
-			fmt.Printf("Failed to send welcome email to %s: %v\n", subscriber.Email, err)
-		}
-	}()
+	if send {
+		baseURL := getBaseURL(c, db)
+		go func() {
+			if err := sendConfirmationEmail(db, subscriber.Email, baseURL); err != nil {
+				fmt.Printf("Failed to send confirmation email to %s: %v\n", subscriber.Email, err)
+			}
+		}()
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":            "Successfully subscribed to newsletter",
-		"email":              subscriber.Email,
-		"already_subscribed": false,
+		"message":              "Please check your inbox to confirm your subscription",
+		"email":                subscriber.Email,
+		"already_subscribed":   false,
+		"confirmation_pending": true,
 	})
 }
 
-// UnsubscribeFromNewsletter handles newsletter unsubscription requests
+// ConfirmNewsletterSubscription confirms a pending subscription from the emailed link.
+// GET requests (clicked links) get an HTML page, POST requests get JSON.
+func ConfirmNewsletterSubscription(c *gin.Context) {
+	token := c.Query("token")
+	if c.Request.Method == http.MethodPost {
+		var req models.ConfirmSubscriptionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+			return
+		}
+		token = req.Token
+	}
+
+	status, message := confirmSubscription(token)
+
+	if c.Request.Method == http.MethodPost {
+		if status != http.StatusOK {
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+		c.JSON(status, gin.H{"message": message})
+		return
+	}
+
+	c.Data(status, "text/html; charset=utf-8", []byte(renderConfirmationPage(message)))
+}
+
+// confirmSubscription validates a confirmation token and confirms the subscriber
+func confirmSubscription(token string) (int, string) {
+	if token == "" {
+		return http.StatusBadRequest, "The confirmation link is missing its token."
+	}
+
+	email, err := middleware.ValidateLinkToken(token, middleware.TokenPurposeNewsletterConfirm)
+	if err != nil {
+		return http.StatusBadRequest, "This confirmation link is invalid or has expired. Please subscribe again."
+	}
+
+	db := database.GetDB()
+	subscriber, confirmed, err := models.ConfirmSubscription(db, email)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return http.StatusNotFound, "This subscription no longer exists. Please subscribe again."
+		}
+		return http.StatusInternalServerError, "Failed to confirm subscription."
+	}
+
+	// Welcome the subscriber only on the first confirmation
+	if confirmed {
+		go func() {
+			if err := sendWelcomeEmail(db, subscriber.Email); err != nil {
+				fmt.Printf("Failed to send welcome email to %s: %v\n", subscriber.Email, err)
+			}
+		}()
+	}
+
+	return http.StatusOK, "Your subscription is confirmed. Thanks for subscribing!"
+}
+
+// renderConfirmationPage returns a minimal HTML page for the confirmation result
+func renderConfirmationPage(message string) string {
+	title := "Newsletter"
+	if settings, err := models.GetOrCreateSettings(database.GetDB()); err == nil && settings.Title != "" {
+		title = settings.Title
+	}
+
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>%s</title></head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 80px auto; padding: 20px; text-align: center; color: #333;">
+    <h1 style="font-size: 24px;">%s</h1>
+    <p style="font-size: 16px;">%s</p>
+</body>
+</html>`, html.EscapeString(title), html.EscapeString(title), html.EscapeString(message))
+}
+
+// UnsubscribeFromNewsletter handles unsubscription requests from the signed link in newsletters
 func UnsubscribeFromNewsletter(c *gin.Context) {
 	var req models.UnsubscribeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsubscribe token is required"})
+		return
+	}
+
+	email, err := middleware.ValidateLinkToken(req.Token, middleware.TokenPurposeNewsletterUnsubscribe)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unsubscribe link"})
 		return
 	}
 
 	db := database.GetDB()
-	err := models.Unsubscribe(db, req.Email)
+	err = models.Unsubscribe(db, email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe from newsletter"})
 		return
@@ -103,7 +208,31 @@ func UnsubscribeFromNewsletter(c *gin.Context) {
 	})
 }
 
-// GetNewsletterStats returns newsletter subscription statistics
+// OneClickUnsubscribe handles RFC 8058 one-click unsubscribes posted by mail clients
+// to the List-Unsubscribe URL. The token comes from the URL, the form body is ignored.
+func OneClickUnsubscribe(c *gin.Context) {
+	email, err := middleware.ValidateLinkToken(c.Query("token"), middleware.TokenPurposeNewsletterUnsubscribe)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unsubscribe link"})
+		return
+	}
+
+	db := database.GetDB()
+	if err := models.Unsubscribe(db, email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe from newsletter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully unsubscribed from newsletter",
+	})
+}
+
+// Number of recent campaigns included in the newsletter stats
+const newsletterStatsCampaignLimit = 20
+
+// GetNewsletterStats returns newsletter subscription statistics and open/click stats of
+// tracked campaigns
 func GetNewsletterStats(c *gin.Context) {
 	db := database.GetDB()
 	count, err := models.GetActiveSubscriberCount(db)
@@ -112,8 +241,23 @@ func GetNewsletterStats(c *gin.Context) {
 		return
 	}
 
+	// Open and click stats of tracked campaigns, per campaign and per event
+	campaigns, err := models.GetCampaignEngagement(db, newsletterStatsCampaignLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get newsletter stats"})
+		return
+	}
+	events, err := models.GetEventEngagement(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get newsletter stats"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"active_subscribers": count,
+		"tracking_enabled":   trackingEnabled(db),
+		"campaigns":          campaigns,
+		"events":             events,
 	})
 }
 
@@ -142,7 +286,7 @@ func CheckSubscriptionStatus(c *gin.Context) {
 	}
 
 	db := database.GetDB()
-	_, err := models.FindSubscriberByEmail(db, email)
+	subscriber, err := models.FindSubscriberByEmail(db, email)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusOK, gin.H{
@@ -157,7 +301,8 @@ func CheckSubscriptionStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"subscribed": true,
-		"active":     true,
+		"active":     subscriber.IsConfirmed(),
+		"status":     subscriber.Status,
 	})
 }
 
@@ -263,7 +408,7 @@ func GetNewsletterSubscribersPaginated(c *gin.Context) {
 func sendWelcomeEmail(db *gorm.DB, email string) error {
 	// Get mail settings
 	mailSettings, err := models.GetOrCreateMailSettings(db)
-	if err != nil || mailSettings.SMTPHost == "" || mailSettings.FromEmail == "" {
+	if err != nil || mailSettings.FromEmail == "" {
 		return fmt.Errorf("mail settings not configured")
 	}
 
@@ -290,10 +435,7 @@ func sendWelcomeEmail(db *gorm.DB, email string) error {
 	}
 
 	// Use baseURL for unsubscribe (not projectURL which is the external website)
-	unsubscribeURL := fmt.Sprintf("%s/unsubscribe?email=%s", baseURL, email)
-	if baseURL == "" {
-		unsubscribeURL = fmt.Sprintf("/unsubscribe?email=%s", email)
-	}
+	unsubscribeURL, oneClickURL := services.NewsletterUnsubscribeURLs(baseURL, email)
 
 	// Get welcome email template and subject (check for custom template first)
 	welcomeTemplate := getWelcomeEmailTemplate()
@@ -310,42 +452,47 @@ func sendWelcomeEmail(db *gorm.DB, email string) error {
 	content := strings.ReplaceAll(welcomeTemplate, "{{project_name}}", projectName)
 	content = strings.ReplaceAll(content, "{{project_url}}", projectURL)
 	content = strings.ReplaceAll(content, "{{unsubscribe_url}}", unsubscribeURL)
+	content = strings.ReplaceAll(content, "{{preferences_url}}", services.NewsletterPreferencesURL(baseURL, email))
 
-	// Prepare email
-	fromName := mailSettings.FromName
-	if fromName == "" {
-		fromName = projectName
-	}
+	return services.NewEmailService().SendEmail(email, welcomeSubject, content, oneClickURL)
+}
 
-	from := fmt.Sprintf("%s <%s>", fromName, mailSettings.FromEmail)
+// sendConfirmationEmail sends the double opt-in link to a pending subscriber
+func sendConfirmationEmail(db *gorm.DB, email, baseURL string) error {
+	token, err := middleware.GenerateLinkToken(middleware.TokenPurposeNewsletterConfirm, email, confirmationTokenTTL)
+	if err != nil {
+		return fmt.Errorf("failed to generate confirmation token: %v", err)
+	}
 
-	// Prepare message
-	message := fmt.Sprintf("From: %s\r\n", from)
-	message += fmt.Sprintf("To: %s\r\n", email)
-	message += fmt.Sprintf("Subject: %s\r\n", welcomeSubject)
-	message += "MIME-Version: 1.0\r\n"
-	message += "Content-Type: text/html; charset=UTF-8\r\n"
-	message += "\r\n"
-	message += content
+	projectSettings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		return fmt.Errorf("failed to get project settings: %v", err)
+	}
 
-	// Determine authentication
-	var auth smtp.Auth
-	if mailSettings.SMTPUsername != "" {
-		auth = smtp.PlainAuth("", mailSettings.SMTPUsername, mailSettings.SMTPPassword, mailSettings.SMTPHost)
+	projectName := projectSettings.Title
+	if projectName == "" {
+		projectName = "ShipShipShip"
 	}
 
-	// Send email based on encryption type
-	addr := fmt.Sprintf("%s:%d", mailSettings.SMTPHost, mailSettings.SMTPPort)
+	confirmURL := fmt.Sprintf("%s/newsletter/confirm?token=%s", baseURL, url.QueryEscape(token))
 
-	switch strings.ToLower(mailSettings.SMTPEncryption) {
-	case "ssl":
-		return utils.SendMailWithSSL(addr, auth, mailSettings.FromEmail, []string{email}, []byte(message))
-	case "tls":
-		return utils.SendMailWithTLS(addr, auth, mailSettings.FromEmail, []string{email}, []byte(message))
-	default:
-		// No encryption
-		return smtp.SendMail(addr, auth, mailSettings.FromEmail, []string{email}, []byte(message))
+	// Use the custom confirmation template when one was saved
+	subject := constants.SubjectConfirmation
+	content := constants.TemplateConfirmation
+	if customTemplate, err := models.GetEmailTemplate(db, constants.TemplateTypeConfirmation); err == nil {
+		subject = customTemplate.Subject
+		content = customTemplate.Content
+	} else if err != gorm.ErrRecordNotFound {
+		fmt.Printf("Warning: Failed to load custom confirmation template: %v\n", err)
 	}
+
+	replacer := strings.NewReplacer(
+		"{{project_name}}", projectName,
+		"{{project_url}}", projectSettings.WebsiteURL,
+		"{{confirm_url}}", confirmURL,
+	)
+
+	return services.NewEmailService().SendEmail(email, replacer.Replace(subject), replacer.Replace(content), "")
 }
 
 // getWelcomeEmailTemplate returns the default welcome email template
@@ -404,10 +551,22 @@ func UpdateEmailTemplates(c *gin.Context) {
 
 	db := database.GetDB()
 
+	// Snapshot existing templates for the audit log
+	before := make(map[string]interface{})
+	after := make(map[string]interface{})
+	if existing, err := models.GetAllEmailTemplates(db); err == nil {
+		for templateType, template := range existing {
+			snapshot := map[string]string{"subject": template.Subject, "content": template.Content}
+			before[templateType] = snapshot
+			after[templateType] = snapshot
+		}
+	}
+
 	// Save each template
 	for templateType, template := range req.Templates {
 		if templateType != constants.TemplateTypeEvent &&
-			templateType != constants.TemplateTypeWelcome {
+			templateType != constants.TemplateTypeWelcome &&
+			templateType != constants.TemplateTypeConfirmation {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template type: " + templateType})
 			return
 		}
@@ -417,8 +576,11 @@ func UpdateEmailTemplates(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save " + templateType + " template"})
 			return
 		}
+		after[templateType] = map[string]string{"subject": template.Subject, "content": template.Content}
 	}
 
+	recordAudit(c, models.AuditActionUpdate, "email_templates", "all", before, after)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Email templates updated successfully"})
 }
 
@@ -444,6 +606,8 @@ func DeleteNewsletterSubscriber(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, "subscriber", email, gin.H{"email": email}, nil)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Subscriber deleted successfully",
 	})
@@ -495,6 +659,8 @@ func UpdateNewsletterAutomationSettings(c *gin.Context) {
 		return
 	}
 
+	before := *currentSettings
+
 	// Update enabled status if provided
 	enabled := currentSettings.Enabled
 	if req.Enabled != nil {
@@ -524,6 +690,8 @@ func UpdateNewsletterAutomationSettings(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionUpdate, "newsletter_automation", updatedSettings.ID, before, updatedSettings)
+
 	// Parse the JSON trigger statuses for response
 	var parsedTriggerStatuses []string
 	if err := json.Unmarshal([]byte(updatedSettings.TriggerStatuses), &parsedTriggerStatuses); err != nil {
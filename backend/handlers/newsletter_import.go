@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Import files larger than this are rejected
+const maxSubscriberImportSize = 10 << 20
+
+// Column names recognized in the header row of an import CSV
+var importEmailColumns = map[string]bool{"email": true, "email_address": true, "email address": true, "e-mail": true}
+
+// ImportNewsletterSubscribers adds subscribers from a CSV or JSON file, uploaded as the
+// "file" form field or sent as the request body. With ?dry_run=true nothing is saved and
+// the report shows what the import would do.
+func ImportNewsletterSubscribers(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	data, isJSON, err := readSubscriberImport(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rows []models.SubscriberImportRow
+	if isJSON {
+		rows, err = parseSubscriberImportJSON(data)
+	} else {
+		rows, err = parseSubscriberImportCSV(data)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The file contains no subscribers"})
+		return
+	}
+
+	db := database.GetDB()
+	report, err := models.ImportSubscribers(db, rows, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import subscribers"})
+		return
+	}
+
+	if !dryRun && report.Created > 0 {
+		recordAudit(c, models.AuditActionImport, "subscriber", "", nil, gin.H{
+			"created": report.Created,
+		})
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// readSubscriberImport returns the uploaded file or request body and whether it is JSON
+func readSubscriberImport(c *gin.Context) ([]byte, bool, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSubscriberImportSize)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, false, errors.New("No file uploaded")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, false, errors.New("Failed to read uploaded file")
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, false, errors.New("Failed to read uploaded file")
+		}
+		isJSON := strings.EqualFold(filepath.Ext(header.Filename), ".json") ||
+			strings.HasPrefix(header.Header.Get("Content-Type"), "application/json")
+		return data, isJSON, nil
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("File is too large (max %d MB)", maxSubscriberImportSize>>20)
+	}
+	return data, c.ContentType() == "application/json", nil
+}
+
+// parseSubscriberImportCSV reads a CSV with a header row naming the email, status and
+// subscribed_at columns, or a headerless file with one address per line
+func parseSubscriberImportCSV(data []byte) ([]models.SubscriberImportRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	emailColumn, statusColumn, dateColumn := 0, -1, -1
+	var rows []models.SubscriberImportRow
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first && hasEmailHeader(record) {
+			emailColumn = -1
+			for i, name := range record {
+				switch name = strings.ToLower(strings.TrimSpace(name)); {
+				case importEmailColumns[name] && emailColumn < 0:
+					emailColumn = i
+				case name == "status":
+					statusColumn = i
+				case name == "subscribed_at" || name == "subscribed" || name == "created_at":
+					dateColumn = i
+				}
+			}
+			continue
+		}
+
+		// Skip blank lines
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		row := models.SubscriberImportRow{Line: line, Email: csvField(record, emailColumn)}
+		row.Status = csvField(record, statusColumn)
+		row.SubscribedAt = csvField(record, dateColumn)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func hasEmailHeader(record []string) bool {
+	for _, name := range record {
+		if importEmailColumns[strings.ToLower(strings.TrimSpace(name))] {
+			return true
+		}
+	}
+	return false
+}
+
+func csvField(record []string, column int) string {
+	if column < 0 || column >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column])
+}
+
+// parseSubscriberImportJSON reads an array of subscriber objects or plain addresses,
+// optionally wrapped in {"subscribers": [...]}
+func parseSubscriberImportJSON(data []byte) ([]models.SubscriberImportRow, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var wrapped struct {
+			Subscribers []json.RawMessage `json:"subscribers"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, errors.New("Invalid JSON: expected an array of subscribers")
+		}
+		entries = wrapped.Subscribers
+	}
+
+	rows := make([]models.SubscriberImportRow, len(entries))
+	for i, entry := range entries {
+		var email string
+		if err := json.Unmarshal(entry, &email); err == nil {
+			rows[i] = models.SubscriberImportRow{Email: email}
+		} else if err := json.Unmarshal(entry, &rows[i]); err != nil {
+			return nil, fmt.Errorf("Invalid JSON: entry %d is not a subscriber", i+1)
+		}
+		rows[i].Line = i + 1
+	}
+	return rows, nil
+}
+
+// ExportNewsletterSubscribers streams all subscribers as CSV, optionally only those with
+// the given status. The columns match what ImportNewsletterSubscribers reads.
+func ExportNewsletterSubscribers(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.SubscriberStatusConfirmed && status != models.SubscriberStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	db := database.GetDB()
+	query := db.Model(&models.NewsletterSubscriber{}).Order("id ASC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="newsletter-subscribers.csv"`)
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"email", "status", "subscribed_at", "confirmed_at"})
+
+	var batch []models.NewsletterSubscriber
+	result := query.FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, subscriber := range batch {
+			confirmedAt := ""
+			if subscriber.ConfirmedAt != nil {
+				confirmedAt = subscriber.ConfirmedAt.UTC().Format(time.RFC3339)
+			}
+			writer.Write([]string{
+				subscriber.Email,
+				subscriber.Status,
+				subscriber.SubscribedAt.UTC().Format(time.RFC3339),
+				confirmedAt,
+			})
+		}
+		writer.Flush()
+		c.Writer.Flush()
+		return writer.Error()
+	})
+	if result.Error != nil {
+		// Headers are already sent, so the truncated file is all the client gets
+		c.Error(result.Error)
+	}
+	writer.Flush()
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"shipshipship/database"
+	"shipshipship/middleware"
+	"shipshipship/models"
+	"shipshipship/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errUnknownTopic is returned when preferences name a tag or category that doesn't exist
+var errUnknownTopic = errors.New("unknown tag or category")
+
+// preferenceCenterTemplate renders the subscriber preference center
+var preferenceCenterTemplate = template.Must(template.New("preferences").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Title}} - Newsletter preferences</title></head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 60px auto; padding: 20px; color: #333;">
+    <h1 style="font-size: 24px;">Newsletter preferences</h1>
+    {{if .Message}}<p style="padding: 10px; background: #f3f4f6; border-radius: 6px;">{{.Message}}</p>{{end}}
+    {{if .Email}}
+    <p>Choose what <strong>{{.Email}}</strong> hears about. Leave a section empty to get everything.</p>
+    <form method="POST">
+        <input type="hidden" name="token" value="{{.Token}}">
+        {{if .Tags}}
+        <h2 style="font-size: 18px;">Tags</h2>
+        {{range .Tags}}<label style="display: block; margin: 4px 0;"><input type="checkbox" name="tag_ids" value="{{.ID}}"{{if .Selected}} checked{{end}}> {{.Label}}</label>
+        {{end}}{{end}}
+        {{if .Categories}}
+        <h2 style="font-size: 18px;">Categories</h2>
+        {{range .Categories}}<label style="display: block; margin: 4px 0;"><input type="checkbox" name="categories" value="{{.ID}}"{{if .Selected}} checked{{end}}> {{.Label}}</label>
+        {{end}}{{end}}
+        <button type="submit" style="margin-top: 20px; padding: 10px 20px; background: #2563eb; color: #fff; border: none; border-radius: 6px; cursor: pointer;">Save preferences</button>
+    </form>
+    {{if .UnsubscribeURL}}<p style="margin-top: 30px; font-size: 14px;"><a href="{{.UnsubscribeURL}}" style="color: #6b7280;">Unsubscribe from all emails</a></p>{{end}}
+    {{end}}
+</body>
+</html>`))
+
+// preferenceOption is a checkbox on the preference center page
+type preferenceOption struct {
+	ID       string
+	Label    string
+	Selected bool
+}
+
+// GetSubscriberPreferences returns a subscriber's topic preferences and the available topics
+func GetSubscriberPreferences(c *gin.Context) {
+	subscriber, ok := loadPreferencesSubscriber(c, c.Query("token"))
+	if !ok {
+		return
+	}
+
+	var tags []models.Tag
+	if err := database.GetDB().Order("name ASC").Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":                subscriber.Email,
+		"tag_ids":              subscriber.TagIDList(),
+		"categories":           subscriber.CategoryList(),
+		"available_tags":       tags,
+		"available_categories": currentThemeCategories(),
+	})
+}
+
+// UpdateSubscriberPreferences saves a subscriber's topic preferences
+func UpdateSubscriberPreferences(c *gin.Context) {
+	var req models.UpdateSubscriberPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Preferences token is required"})
+		return
+	}
+
+	subscriber, ok := loadPreferencesSubscriber(c, req.Token)
+	if !ok {
+		return
+	}
+
+	if err := saveSubscriberPreferences(subscriber, req.TagIDs, req.Categories); err != nil {
+		if err == errUnknownTopic {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tag or category"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Preferences saved",
+		"tag_ids":    subscriber.TagIDList(),
+		"categories": subscriber.CategoryList(),
+	})
+}
+
+// ServePreferenceCenter renders the preference center page linked from newsletters.
+// The page posts its form back to the same URL.
+func ServePreferenceCenter(c *gin.Context) {
+	token := c.Query("token")
+	if c.Request.Method == http.MethodPost {
+		token = c.PostForm("token")
+	}
+
+	data := gin.H{"Title": "Newsletter", "Token": token}
+	db := database.GetDB()
+	if settings, err := models.GetOrCreateSettings(db); err == nil && settings.Title != "" {
+		data["Title"] = settings.Title
+	}
+
+	email, err := middleware.ValidateLinkToken(token, middleware.TokenPurposeNewsletterPreferences)
+	if err != nil {
+		data["Message"] = "This preferences link is invalid. Please use the link from one of our emails."
+		renderPreferenceCenter(c, http.StatusBadRequest, data)
+		return
+	}
+
+	subscriber, err := models.FindSubscriberByEmail(db, email)
+	if err != nil {
+		data["Message"] = "You are no longer subscribed to our newsletter."
+		renderPreferenceCenter(c, http.StatusNotFound, data)
+		return
+	}
+
+	status := http.StatusOK
+	if c.Request.Method == http.MethodPost {
+		tagIDs := []uint{}
+		for _, raw := range c.PostFormArray("tag_ids") {
+			if id, err := strconv.ParseUint(raw, 10, 32); err == nil {
+				tagIDs = append(tagIDs, uint(id))
+			}
+		}
+		if err := saveSubscriberPreferences(subscriber, tagIDs, c.PostFormArray("categories")); err != nil {
+			status = http.StatusBadRequest
+			data["Message"] = "Your preferences could not be saved. Please try again."
+		} else {
+			data["Message"] = "Your preferences have been saved."
+		}
+	}
+
+	data["Email"] = subscriber.Email
+	data["Tags"], data["Categories"] = preferenceOptions(db, subscriber)
+	data["UnsubscribeURL"], _ = services.NewsletterUnsubscribeURLs(getBaseURL(c, db), subscriber.Email)
+
+	renderPreferenceCenter(c, status, data)
+}
+
+// renderPreferenceCenter writes the preference center page
+func renderPreferenceCenter(c *gin.Context, status int, data gin.H) {
+	var page bytes.Buffer
+	if err := preferenceCenterTemplate.Execute(&page, data); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render preferences page")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
+
+// loadPreferencesSubscriber validates a preferences token and loads its subscriber
+func loadPreferencesSubscriber(c *gin.Context, token string) (*models.NewsletterSubscriber, bool) {
+	email, err := middleware.ValidateLinkToken(token, middleware.TokenPurposeNewsletterPreferences)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid preferences link"})
+		return nil, false
+	}
+
+	subscriber, err := models.FindSubscriberByEmail(database.GetDB(), email)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscriber not found"})
+		return nil, false
+	}
+
+	return subscriber, true
+}
+
+// saveSubscriberPreferences validates and stores preferences; unknown topics return errUnknownTopic
+func saveSubscriberPreferences(subscriber *models.NewsletterSubscriber, tagIDs []uint, categories []string) error {
+	db := database.GetDB()
+
+	if len(tagIDs) > 0 {
+		var count int64
+		if err := db.Model(&models.Tag{}).Where("id IN ?", tagIDs).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(uniqueUints(tagIDs))) {
+			return errUnknownTopic
+		}
+	}
+
+	known := map[string]bool{}
+	for _, category := range currentThemeCategories() {
+		known[category.ID] = true
+	}
+	for _, category := range categories {
+		if !known[category] {
+			return errUnknownTopic
+		}
+	}
+
+	subscriber.SetPreferences(uniqueUints(tagIDs), categories)
+	return db.Model(subscriber).Updates(map[string]interface{}{
+		"tag_ids":    subscriber.TagIDs,
+		"categories": subscriber.Categories,
+	}).Error
+}
+
+// preferenceOptions lists every tag and category with the subscriber's choices checked
+func preferenceOptions(db *gorm.DB, subscriber *models.NewsletterSubscriber) ([]preferenceOption, []preferenceOption) {
+	selectedTags := map[uint]bool{}
+	for _, id := range subscriber.TagIDList() {
+		selectedTags[id] = true
+	}
+	selectedCategories := map[string]bool{}
+	for _, id := range subscriber.CategoryList() {
+		selectedCategories[id] = true
+	}
+
+	tagOptions := []preferenceOption{}
+	var tags []models.Tag
+	if err := db.Order("name ASC").Find(&tags).Error; err == nil {
+		for _, tag := range tags {
+			tagOptions = append(tagOptions, preferenceOption{
+				ID:       fmt.Sprint(tag.ID),
+				Label:    tag.Name,
+				Selected: selectedTags[tag.ID],
+			})
+		}
+	}
+
+	categoryOptions := []preferenceOption{}
+	for _, category := range currentThemeCategories() {
+		categoryOptions = append(categoryOptions, preferenceOption{
+			ID:       category.ID,
+			Label:    category.Label,
+			Selected: selectedCategories[category.ID],
+		})
+	}
+
+	return tagOptions, categoryOptions
+}
+
+// currentThemeCategories returns the categories of the installed theme, if any
+func currentThemeCategories() []models.ThemeCategory {
+	manifest, err := models.LoadThemeManifest("./data/themes/current")
+	if err != nil || manifest.Categories == nil {
+		return []models.ThemeCategory{}
+	}
+	return manifest.Categories
+}
+
+// uniqueUints removes duplicate IDs while keeping their order
+func uniqueUints(ids []uint) []uint {
+	seen := map[uint]bool{}
+	unique := []uint{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
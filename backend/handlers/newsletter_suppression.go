@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/email"
+	"shipshipship/models"
+	"shipshipship/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounce webhook payloads larger than this are rejected
+const maxBounceWebhookSize = 1 << 20
+
+// ReceiveBounceWebhook accepts bounce and complaint notifications from a mail provider
+// and suppresses hard-bounced and complaining addresses. The source is postmark,
+// sendgrid, mailgun, ses or generic; requests must carry the bounce webhook secret
+// (see bounceWebhookSecret).
+func ReceiveBounceWebhook(c *gin.Context) {
+	source := c.Param("source")
+	if !email.IsBounceWebhookSource(source) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown bounce source"})
+		return
+	}
+
+	db := database.GetDB()
+	settings, err := models.GetOrCreateMailSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mail settings"})
+		return
+	}
+	secret := bounceWebhookSecret(c)
+	if settings.BounceWebhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(settings.BounceWebhookSecret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook secret"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBounceWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Payload too large"})
+		return
+	}
+
+	// Amazon SNS asks to confirm the subscription before sending notifications
+	if source == email.ProviderSES {
+		if subscribeURL := email.SNSSubscribeURL(body); subscribeURL != "" {
+			if err := confirmSNSSubscription(subscribeURL); err != nil {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to confirm SNS subscription"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Subscription confirmed"})
+			return
+		}
+	}
+
+	bounces, err := email.ParseBounceWebhook(source, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suppressed, err := services.RecordBounces(db, source, bounces)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record bounces"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": len(bounces), "suppressed": suppressed})
+}
+
+// bounceWebhookSecret returns the secret a bounce webhook request carries: the password
+// of HTTP basic auth, the X-Webhook-Secret header, or the older ?secret= parameter,
+// which ends up in access logs
+func bounceWebhookSecret(c *gin.Context) string {
+	if _, password, ok := c.Request.BasicAuth(); ok {
+		return password
+	}
+	if secret := c.GetHeader("X-Webhook-Secret"); secret != "" {
+		return secret
+	}
+	return c.Query("secret")
+}
+
+func confirmSNSSubscription(subscribeURL string) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(subscribeURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SNS returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetEmailSuppressions returns the suppression list, optionally filtered by reason
+// and an address search
+func GetEmailSuppressions(c *gin.Context) {
+	page, limit := parsePagination(c, 20)
+
+	db := database.GetDB()
+	suppressions, total, err := models.GetSuppressionsPaginated(db, c.Query("reason"), strings.TrimSpace(c.Query("search")), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suppressions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suppressions": suppressions,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+		"total_pages":  (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// CreateEmailSuppression adds an address to the suppression list by hand
+func CreateEmailSuppression(c *gin.Context) {
+	var req struct {
+		Email  string `json:"email" binding:"required,email"`
+		Detail string `json:"detail"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Valid email address is required"})
+		return
+	}
+
+	db := database.GetDB()
+	suppression, err := models.SuppressEmail(db, req.Email, models.SuppressionManual, "admin", strings.TrimSpace(req.Detail))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add suppression"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, "email_suppression", suppression.ID, nil, suppression)
+	c.JSON(http.StatusCreated, suppression)
+}
+
+// DeleteEmailSuppression removes an address from the suppression list so it can be
+// mailed again
+func DeleteEmailSuppression(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suppression ID"})
+		return
+	}
+
+	db := database.GetDB()
+	var suppression models.EmailSuppression
+	if err := db.First(&suppression, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suppression not found"})
+		return
+	}
+
+	if err := db.Delete(&suppression).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete suppression"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, "email_suppression", suppression.ID, suppression, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Suppression removed"})
+}
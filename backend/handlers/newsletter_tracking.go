@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"shipshipship/database"
+	"shipshipship/middleware"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 1x1 transparent GIF served by the open tracking pixel
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackEmailOpen serves the tracking pixel and records an open of a tracked newsletter.
+// The pixel is always returned so broken or disabled tracking never shows in the email.
+func TrackEmailOpen(c *gin.Context) {
+	db := database.GetDB()
+	if trackingEnabled(db) {
+		if job, ok := trackedEmailJob(db, c.Query("t")); ok {
+			if err := models.RecordEmailOpen(db, job); err != nil {
+				c.Error(err)
+			}
+		}
+	}
+
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, private")
+	c.Header("Pragma", "no-cache")
+	c.Data(http.StatusOK, "image/gif", trackingPixel)
+}
+
+// TrackEmailClick records a link click of a tracked newsletter and redirects to the link.
+// Only links that are part of the recipient's campaign are followed, so the endpoint
+// can't be used as an open redirect.
+func TrackEmailClick(c *gin.Context) {
+	target := c.Query("u")
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(renderConfirmationPage("This link is invalid.")))
+		return
+	}
+
+	db := database.GetDB()
+	job, ok := trackedEmailJob(db, c.Query("t"))
+	if !ok || !campaignContainsLink(db, job.CampaignID, target) {
+		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte(renderConfirmationPage("This link is invalid.")))
+		return
+	}
+
+	if trackingEnabled(db) {
+		if err := models.RecordEmailClick(db, job, target); err != nil {
+			c.Error(err)
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// trackingEnabled reports whether open and click tracking is switched on
+func trackingEnabled(db *gorm.DB) bool {
+	settings, err := models.GetOrCreateMailSettings(db)
+	return err == nil && settings.TrackingEnabled
+}
+
+// trackedEmailJob returns the email job identified by a tracking token
+func trackedEmailJob(db *gorm.DB, token string) (*models.EmailJob, bool) {
+	if token == "" {
+		return nil, false
+	}
+	subject, err := middleware.ValidateLinkToken(token, middleware.TokenPurposeNewsletterTracking)
+	if err != nil {
+		return nil, false
+	}
+	jobID, err := strconv.ParseUint(subject, 10, 32)
+	if err != nil {
+		return nil, false
+	}
+
+	var job models.EmailJob
+	if err := db.First(&job, jobID).Error; err != nil {
+		return nil, false
+	}
+	return &job, true
+}
+
+// campaignContainsLink checks that target is one of the tracked links of a campaign
+func campaignContainsLink(db *gorm.DB, campaignID uint, target string) bool {
+	var campaign models.EmailCampaign
+	if err := db.Select("content").First(&campaign, campaignID).Error; err != nil {
+		return false
+	}
+
+	// u is the last query parameter of tracked links, so it ends at the closing quote
+	escaped := "u=" + url.QueryEscape(target)
+	return strings.Contains(campaign.Content, escaped+`"`) || strings.Contains(campaign.Content, escaped+`'`)
+}
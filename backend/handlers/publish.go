@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -11,6 +10,7 @@ import (
 	"shipshipship/constants"
 	"shipshipship/database"
 	"shipshipship/email"
+	"shipshipship/middleware"
 	"shipshipship/models"
 	"shipshipship/services"
 
@@ -48,16 +48,33 @@ func GetEventPublishStatus(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"is_public":        event.IsPublic,
-		"has_public_url":   event.HasPublicUrl,
-		"email_sent":       event.Publication.EmailSent,
-		"email_sent_at":    event.Publication.EmailSentAt,
-		"email_subject":    event.Publication.EmailSubject,
-		"email_template":   event.Publication.EmailTemplate,
-		"subscriber_count": event.Publication.SubscriberCount,
+		"is_public":         event.IsPublic,
+		"has_public_url":    event.HasPublicUrl,
+		"publish_at":        event.PublishAt,
+		"unpublish_at":      event.UnpublishAt,
+		"notify_on_publish": event.NotifyOnPublish,
+		"email_sent":        event.Publication.EmailSent,
+		"email_sent_at":     event.Publication.EmailSentAt,
+		"email_subject":     event.Publication.EmailSubject,
+		"email_template":    event.Publication.EmailTemplate,
+		"subscriber_count":  event.Publication.SubscriberCount,
 	})
 }
 
+// parseScheduleTime parses an RFC3339 schedule timestamp; an empty string clears the schedule
+func parseScheduleTime(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
 // UpdateEventPublicStatus updates the public visibility of an event
 func UpdateEventPublicStatus(c *gin.Context) {
 	eventIDStr := c.Param("id")
@@ -75,6 +92,20 @@ func UpdateEventPublicStatus(c *gin.Context) {
 
 	db := database.GetDB()
 
+	var event models.Event
+	if err := db.First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	before := gin.H{
+		"is_public":         event.IsPublic,
+		"has_public_url":    event.HasPublicUrl,
+		"publish_at":        event.PublishAt,
+		"unpublish_at":      event.UnpublishAt,
+		"notify_on_publish": event.NotifyOnPublish,
+	}
+
 	// Prepare updates map
 	updates := make(map[string]interface{})
 
@@ -86,6 +117,40 @@ func UpdateEventPublicStatus(c *gin.Context) {
 		updates["has_public_url"] = *req.HasPublicUrl
 	}
 
+	publishAt := event.PublishAt
+	if req.PublishAt != nil {
+		publishAt, err = parseScheduleTime(*req.PublishAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid publish_at, expected RFC3339 timestamp"})
+			return
+		}
+		updates["publish_at"] = publishAt
+
+		// Scheduling a future publish hides the event until then, unless visibility was set explicitly
+		if publishAt != nil && publishAt.After(time.Now()) && req.IsPublic == nil {
+			updates["is_public"] = false
+		}
+	}
+
+	unpublishAt := event.UnpublishAt
+	if req.UnpublishAt != nil {
+		unpublishAt, err = parseScheduleTime(*req.UnpublishAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unpublish_at, expected RFC3339 timestamp"})
+			return
+		}
+		updates["unpublish_at"] = unpublishAt
+	}
+
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unpublish_at must be after publish_at"})
+		return
+	}
+
+	if req.NotifyOnPublish != nil {
+		updates["notify_on_publish"] = *req.NotifyOnPublish
+	}
+
 	// Update the event's status
 	if len(updates) > 0 {
 		if err := db.Model(&models.Event{}).Where("id = ?", eventID).Updates(updates).Error; err != nil {
@@ -94,6 +159,21 @@ func UpdateEventPublicStatus(c *gin.Context) {
 		}
 	}
 
+	after := gin.H{}
+	for key, value := range before {
+		after[key] = value
+	}
+	for key, value := range updates {
+		after[key] = value
+	}
+	recordAudit(c, models.AuditActionUpdate, "event_publication", event.ID, before, after)
+
+	if !event.IsPublic && after["is_public"] == true {
+		db.Preload("Tags").First(&event, event.ID)
+		services.DispatchWebhookEvent(models.WebhookEventPublished, gin.H{"event": event})
+	}
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Event status updated successfully",
 		"updates": updates,
@@ -188,6 +268,10 @@ func SendEventNewsletter(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
+	if strings.ContainsAny(req.Subject, "\r\n") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subject must not contain line breaks"})
+		return
+	}
 
 	// Use generic event template
 	req.Template = "event"
@@ -203,15 +287,15 @@ func SendEventNewsletter(c *gin.Context) {
 
 	// Note: We allow resending emails, but track the history
 
-	// Get newsletter subscribers
-	subscribers, err := models.GetActiveNewsletterSubscribers(db)
+	// Get the subscribers whose topic preferences match this event
+	subscribers, err := models.GetNewsletterSubscribersForEvent(db, &event)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get newsletter subscribers"})
 		return
 	}
 
 	if len(subscribers) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No active newsletter subscribers found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active newsletter subscribers match this event's tags and categories"})
 		return
 	}
 
@@ -224,77 +308,37 @@ func SendEventNewsletter(c *gin.Context) {
 		return
 	}
 
-	// Send emails to all subscribers
-	emailService := services.NewEmailService()
-	sentCount := 0
-
-	for _, subscriber := range subscribers {
-		// Replace unsubscribe URL in content (use BaseURL, not ProjectURL)
-		unsubscribeURL := fmt.Sprintf("%s/unsubscribe?email=%s", branding.BaseURL, subscriber.Email)
-		if branding.BaseURL == "" {
-			unsubscribeURL = fmt.Sprintf("/unsubscribe?email=%s", subscriber.Email)
-		}
-		personalizedContent := strings.ReplaceAll(req.Content, "{{unsubscribe_url}}", unsubscribeURL)
-
-		err := emailService.SendEmail(subscriber.Email, req.Subject, personalizedContent)
-		if err != nil {
-			// Log the error but continue sending to other subscribers
-			fmt.Printf("Failed to send email to %s: %v\n", subscriber.Email, err)
-			continue
-		}
-		sentCount++
+	recipients := make([]string, len(subscribers))
+	for i, subscriber := range subscribers {
+		recipients[i] = subscriber.Email
 	}
 
-	// Create email history record
-	now := time.Now()
-	historyRecord := &models.EventEmailHistory{
-		EventID:         uint(eventID),
-		EventStatus:     string(event.Status),
-		EmailSubject:    req.Subject,
-		EmailTemplate:   req.Template,
-		SubscriberCount: sentCount,
-		SentAt:          now,
+	// Sending happens in the background email queue; progress is tracked per campaign
+	campaign := models.EmailCampaign{
+		EventID:     event.ID,
+		EventStatus: string(event.Status),
+		Subject:     req.Subject,
+		Content:     req.Content,
+		Template:    req.Template,
+		BaseURL:     branding.BaseURL,
+		CreatedBy:   c.GetString("username"),
 	}
-	if err := db.Create(historyRecord).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save email history"})
+	if err := services.EnqueueCampaign(db, &campaign, recipients); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue newsletter"})
 		return
 	}
 
-	// Newsletter history now uses EventEmailHistory directly (see newsletter.go handler)
-
-	// Update or create publication record (for backward compatibility)
-	if event.Publication == nil {
-		publication := &models.EventPublication{
-			EventID:         uint(eventID),
-			EmailSent:       true,
-			EmailSubject:    req.Subject,
-			EmailContent:    req.Content,
-			EmailTemplate:   req.Template,
-			EmailSentAt:     &now,
-			SubscriberCount: sentCount,
-		}
-		if err := db.Create(publication).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save publication record"})
-			return
-		}
-	} else {
-		updates := map[string]interface{}{
-			"email_sent":       true,
-			"email_subject":    req.Subject,
-			"email_content":    req.Content,
-			"email_template":   req.Template,
-			"email_sent_at":    &now,
-			"subscriber_count": sentCount,
-		}
-		if err := db.Model(event.Publication).Updates(updates).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update publication record"})
-			return
-		}
-	}
+	recordAudit(c, models.AuditActionSend, "newsletter", event.ID, nil, gin.H{
+		"campaign_id":       campaign.ID,
+		"subject":           req.Subject,
+		"template":          req.Template,
+		"total_subscribers": len(subscribers),
+	})
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":           "Newsletter sent successfully",
-		"subscribers_sent":  sentCount,
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":           "Newsletter queued for sending",
+		"campaign_id":       campaign.ID,
+		"subscribers_sent":  0,
 		"total_subscribers": len(subscribers),
 	})
 }
@@ -1,15 +1,46 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// Cookie identities that may react to or vote on one event from the same IP address;
+// further visitors from it share the IP identity
+const maxVisitorsPerIP = 20
+
+// voterIdentity returns the identity a visitor reacts to and votes on an event with.
+// Reactions and votes a cookie visitor gave under their IP identity move to the cookie.
+func voterIdentity(c *gin.Context, db *gorm.DB, eventID uint) string {
+	identity := middleware.VoterIdentity(c)
+	ipIdentity := middleware.VisitorIPIdentity(c)
+	if identity == ipIdentity {
+		return identity
+	}
+
+	others, err := models.CountVisitorIdentities(db, eventID, strings.TrimPrefix(ipIdentity, "ip:"), identity)
+	if err != nil {
+		fmt.Printf("Warning: Failed to count visitors of event %d: %v\n", eventID, err)
+		return ipIdentity
+	}
+	if others >= maxVisitorsPerIP {
+		return ipIdentity
+	}
+	if err := models.ClaimVisitorIdentity(db, eventID, ipIdentity, identity); err != nil {
+		fmt.Printf("Warning: Failed to move reactions of event %d to visitor: %v\n", eventID, err)
+	}
+	return identity
+}
+
 // AddOrRemoveReaction handles adding or removing a reaction (toggle behavior)
 func AddOrRemoveReaction(c *gin.Context) {
 	id := c.Param("id")
@@ -28,15 +59,6 @@ func AddOrRemoveReaction(c *gin.Context) {
 		return
 	}
 
-	// Validate reaction type
-	if !models.IsValidReactionType(req.ReactionType) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reaction type"})
-		return
-	}
-
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 
 	// Check if event exists
@@ -46,9 +68,11 @@ func AddOrRemoveReaction(c *gin.Context) {
 		return
 	}
 
-	// Check if this IP has already reacted with this type
+	identity := voterIdentity(c, db, event.ID)
+
+	// Check if this visitor has already reacted with this type
 	var existingReaction models.EventReaction
-	err = db.Where("event_id = ? AND ip_address = ? AND reaction_type = ?", eventID, clientIP, req.ReactionType).
+	err = db.Where("event_id = ? AND identity = ? AND reaction_type = ?", eventID, identity, req.ReactionType).
 		First(&existingReaction).Error
 
 	if err == nil {
@@ -59,8 +83,9 @@ func AddOrRemoveReaction(c *gin.Context) {
 		}
 
 		// Get updated reaction summary
-		summary := getReactionSummary(db, uint(eventID), clientIP)
+		summary := getReactionSummary(db, uint(eventID), middleware.VisitorIdentities(c))
 
+		middleware.BumpContentVersion()
 		c.JSON(http.StatusOK, gin.H{
 			"message":  "Reaction removed successfully",
 			"removed":  true,
@@ -70,21 +95,30 @@ func AddOrRemoveReaction(c *gin.Context) {
 		return
 	}
 
-	// Reaction doesn't exist, create it
+	// Reaction doesn't exist, create it if it is offered on this event. Removing
+	// works for any reaction so visitors can take back ones no longer offered.
+	if !reactionAvailable(db, &event, req.ReactionType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reaction type"})
+		return
+	}
+
 	reaction := models.EventReaction{
 		EventID:      uint(eventID),
 		ReactionType: req.ReactionType,
-		IPAddress:    clientIP,
+		IPAddress:    middleware.HashIP(c.ClientIP()),
+		Identity:     identity,
 	}
 
-	if err := db.Create(&reaction).Error; err != nil {
+	// A duplicate means a concurrent request of the same visitor added it first
+	if err := db.Create(&reaction).Error; err != nil && !models.IsDuplicateError(err) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add reaction"})
 		return
 	}
 
 	// Get updated reaction summary
-	summary := getReactionSummary(db, uint(eventID), clientIP)
+	summary := getReactionSummary(db, uint(eventID), middleware.VisitorIdentities(c))
 
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Reaction added successfully",
 		"added":    true,
@@ -102,9 +136,6 @@ func GetEventReactions(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 
 	// Check if event exists
@@ -114,12 +145,12 @@ func GetEventReactions(c *gin.Context) {
 		return
 	}
 
-	summary := getReactionSummary(db, uint(eventID), clientIP)
+	summary := getReactionSummary(db, uint(eventID), middleware.VisitorIdentities(c))
 
 	c.JSON(http.StatusOK, summary)
 }
 
-// GetMyReactions returns the current user/IP's reactions for an event
+// GetMyReactions returns the current visitor's reactions for an event
 func GetMyReactions(c *gin.Context) {
 	id := c.Param("id")
 	eventID, err := strconv.ParseUint(id, 10, 32)
@@ -128,18 +159,20 @@ func GetMyReactions(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 
-	// Get user's reactions
+	// Get the visitor's reactions
 	var reactions []models.EventReaction
-	db.Where("event_id = ? AND ip_address = ?", eventID, clientIP).Find(&reactions)
-
-	reactionTypes := make([]models.ReactionType, len(reactions))
-	for i, r := range reactions {
-		reactionTypes[i] = r.ReactionType
+	db.Where("event_id = ? AND identity IN ?", eventID, middleware.VisitorIdentities(c)).Order("id").Find(&reactions)
+
+	// A reaction may be stored under both identities of the visitor
+	reactionTypes := []models.ReactionType{}
+	seen := map[models.ReactionType]bool{}
+	for _, r := range reactions {
+		if !seen[r.ReactionType] {
+			seen[r.ReactionType] = true
+			reactionTypes = append(reactionTypes, r.ReactionType)
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -191,8 +224,8 @@ func MigrateVotesToReactions(c *gin.Context) {
 	for _, vote := range votes {
 		// Check if reaction already exists
 		var existingReaction models.EventReaction
-		err := db.Where("event_id = ? AND ip_address = ? AND reaction_type = ?",
-			vote.EventID, vote.IPAddress, models.ReactionThumbsUp).
+		err := db.Where("event_id = ? AND identity = ? AND reaction_type = ?",
+			vote.EventID, vote.Identity, models.ReactionThumbsUp).
 			First(&existingReaction).Error
 
 		if err == nil {
@@ -205,6 +238,7 @@ func MigrateVotesToReactions(c *gin.Context) {
 			EventID:      vote.EventID,
 			ReactionType: models.ReactionThumbsUp,
 			IPAddress:    vote.IPAddress,
+			Identity:     vote.Identity,
 			CreatedAt:    vote.CreatedAt,
 			UpdatedAt:    vote.UpdatedAt,
 		}
@@ -217,6 +251,7 @@ func MigrateVotesToReactions(c *gin.Context) {
 		migratedCount++
 	}
 
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{
 		"message":        "Migration completed",
 		"migrated_count": migratedCount,
@@ -225,22 +260,43 @@ func MigrateVotesToReactions(c *gin.Context) {
 	})
 }
 
-// GetReactionTypes returns all available reaction types with metadata
+// GetReactionTypes returns the enabled reactions for the current theme. With
+// ?event_id= or ?category= only the reactions offered in that category are listed.
 func GetReactionTypes(c *gin.Context) {
-	reactionTypes := models.ValidReactionTypes()
+	db := database.GetDB()
+
+	var categories []string
+	if eventID := c.Query("event_id"); eventID != "" {
+		var event models.Event
+		if err := db.First(&event, eventID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		categories = eventReactionCategories(db, &event)
+	} else if category := c.Query("category"); category != "" {
+		categories = []string{category}
+	}
+
+	definitions, err := availableReactions(db, categories)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reaction types"})
+		return
+	}
 
 	type ReactionTypeInfo struct {
-		Type  models.ReactionType `json:"type"`
-		Emoji string              `json:"emoji"`
-		Label string              `json:"label"`
+		Type    models.ReactionType `json:"type"`
+		Emoji   string              `json:"emoji"`
+		IconURL string              `json:"icon_url,omitempty"`
+		Label   string              `json:"label"`
 	}
 
-	reactionInfo := make([]ReactionTypeInfo, len(reactionTypes))
-	for i, rt := range reactionTypes {
+	reactionInfo := make([]ReactionTypeInfo, len(definitions))
+	for i, definition := range definitions {
 		reactionInfo[i] = ReactionTypeInfo{
-			Type:  rt,
-			Emoji: models.GetReactionEmoji(rt),
-			Label: models.GetReactionLabel(rt),
+			Type:    definition.Key,
+			Emoji:   definition.Emoji,
+			IconURL: sanitizeImageURL(definition.IconURL),
+			Label:   definition.Label,
 		}
 	}
 
@@ -248,3 +304,50 @@ func GetReactionTypes(c *gin.Context) {
 		"reactions": reactionInfo,
 	})
 }
+
+// availableReactions returns the enabled reactions shown with the current theme in
+// any of the categories (all categories when empty)
+func availableReactions(db *gorm.DB, categories []string) ([]models.ReactionDefinition, error) {
+	definitions, err := models.GetReactionDefinitions(db, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var themeID string
+	if settings, err := models.GetOrCreateSettings(db); err == nil {
+		themeID = settings.CurrentThemeID
+	}
+
+	available := []models.ReactionDefinition{}
+	for _, definition := range definitions {
+		if definition.AppliesTo(themeID, categories) {
+			available = append(available, definition)
+		}
+	}
+	return available, nil
+}
+
+// eventReactionCategories returns the categories of an event's status to match
+// reaction restrictions against
+func eventReactionCategories(db *gorm.DB, event *models.Event) []string {
+	categories, _ := models.GetStatusCategories(db, string(event.Status))
+	if len(categories) == 0 {
+		// Events outside every category only get unrestricted reactions
+		return []string{""}
+	}
+	return categories
+}
+
+// reactionAvailable reports whether a reaction can be given to an event
+func reactionAvailable(db *gorm.DB, event *models.Event, reactionType models.ReactionType) bool {
+	definitions, err := availableReactions(db, eventReactionCategories(db, event))
+	if err != nil {
+		return false
+	}
+	for _, definition := range definitions {
+		if definition.Key == reactionType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"shipshipship/database"
+	"shipshipship/middleware"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetReactionDefinitions returns all reaction definitions, including disabled ones
+func GetReactionDefinitions(c *gin.Context) {
+	db := database.GetDB()
+
+	definitions, err := models.GetReactionDefinitions(db, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reactions"})
+		return
+	}
+	for i := range definitions {
+		definitions[i].IconURL = sanitizeImageURL(definitions[i].IconURL)
+	}
+
+	c.JSON(http.StatusOK, definitions)
+}
+
+// CreateReactionDefinition creates a new reaction
+func CreateReactionDefinition(c *gin.Context) {
+	var req models.CreateReactionDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	definition := models.ReactionDefinition{
+		Key:     models.ReactionType(strings.TrimSpace(string(req.Key))),
+		Emoji:   strings.TrimSpace(req.Emoji),
+		IconURL: strings.TrimSpace(req.IconURL),
+		Label:   strings.TrimSpace(req.Label),
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	definition.SetRestrictions(req.ThemeIDs, req.CategoryIDs)
+
+	if msg := validateReactionDefinition(&definition); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	db := database.GetDB()
+
+	var count int64
+	db.Model(&models.ReactionDefinition{}).Where("`key` = ?", definition.Key).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reaction with same key already exists"})
+		return
+	}
+
+	if req.Order != nil {
+		definition.Order = *req.Order
+	} else {
+		var maxOrder int
+		db.Model(&models.ReactionDefinition{}).Select("COALESCE(MAX(`order`),0)").Scan(&maxOrder)
+		definition.Order = maxOrder + 1
+	}
+
+	if err := db.Create(&definition).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reaction"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, "reaction", definition.ID, nil, definition)
+
+	middleware.BumpContentVersion()
+	c.JSON(http.StatusCreated, definition)
+}
+
+// UpdateReactionDefinition updates a reaction. Changing the key also changes it on
+// the reactions events already received.
+func UpdateReactionDefinition(c *gin.Context) {
+	var req models.UpdateReactionDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+	var definition models.ReactionDefinition
+	if err := db.First(&definition, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reaction not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reaction"})
+		}
+		return
+	}
+
+	before := definition
+	originalKey := definition.Key
+
+	if req.Key != nil {
+		definition.Key = models.ReactionType(strings.TrimSpace(string(*req.Key)))
+	}
+	if req.Emoji != nil {
+		definition.Emoji = strings.TrimSpace(*req.Emoji)
+	}
+	if req.IconURL != nil {
+		definition.IconURL = strings.TrimSpace(*req.IconURL)
+	}
+	if req.Label != nil {
+		definition.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.Order != nil {
+		definition.Order = *req.Order
+	}
+	if req.Enabled != nil {
+		definition.Enabled = *req.Enabled
+	}
+	themeIDs, categoryIDs := definition.ThemeIDList(), definition.CategoryIDList()
+	if req.ThemeIDs != nil {
+		themeIDs = *req.ThemeIDs
+	}
+	if req.CategoryIDs != nil {
+		categoryIDs = *req.CategoryIDs
+	}
+	definition.SetRestrictions(themeIDs, categoryIDs)
+
+	if msg := validateReactionDefinition(&definition); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if definition.Key != originalKey {
+		var count int64
+		db.Model(&models.ReactionDefinition{}).
+			Where("id != ? AND `key` = ?", definition.ID, definition.Key).
+			Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another reaction with this key already exists"})
+			return
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&definition).Error; err != nil {
+			return err
+		}
+		if definition.Key == originalKey {
+			return nil
+		}
+		return tx.Model(&models.EventReaction{}).Unscoped().
+			Where("reaction_type = ?", originalKey).
+			Update("reaction_type", definition.Key).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, "reaction", definition.ID, before, definition)
+
+	middleware.BumpContentVersion()
+	c.JSON(http.StatusOK, definition)
+}
+
+// DeleteReactionDefinition deletes a reaction (blocked once events received it)
+func DeleteReactionDefinition(c *gin.Context) {
+	db := database.GetDB()
+	var definition models.ReactionDefinition
+	if err := db.First(&definition, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reaction not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reaction"})
+		}
+		return
+	}
+
+	// Check usage
+	var reactionCount int64
+	db.Model(&models.EventReaction{}).Where("reaction_type = ?", definition.Key).Count(&reactionCount)
+	if reactionCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete a reaction that events have received, disable it instead"})
+		return
+	}
+
+	if err := db.Delete(&definition).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reaction"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, "reaction", definition.ID, definition, nil)
+
+	middleware.BumpContentVersion()
+	c.JSON(http.StatusOK, gin.H{"message": "Reaction deleted"})
+}
+
+// ReorderReactionDefinitions sets ordering based on provided list.
+// Request body: { "order": [ { "id": 1, "order": 0 }, { "id": 2, "order": 1 } ] }
+func ReorderReactionDefinitions(c *gin.Context) {
+	var req struct {
+		Order []struct {
+			ID    uint `json:"id"`
+			Order int  `json:"order"`
+		} `json:"order"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+
+	// Snapshot current ordering for the audit log
+	var existing []models.ReactionDefinition
+	db.Find(&existing)
+	keys := make(map[uint]models.ReactionType)
+	before := make(map[models.ReactionType]int)
+	after := make(map[models.ReactionType]int)
+	for _, definition := range existing {
+		keys[definition.ID] = definition.Key
+		before[definition.Key] = definition.Order
+		after[definition.Key] = definition.Order
+	}
+
+	for _, item := range req.Order {
+		if err := db.Model(&models.ReactionDefinition{}).
+			Where("id = ?", item.ID).
+			Update("order", item.Order).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction order"})
+			return
+		}
+		if key, ok := keys[item.ID]; ok {
+			after[key] = item.Order
+		}
+	}
+
+	recordAudit(c, models.AuditActionUpdate, "reaction_order", "all", before, after)
+
+	middleware.BumpContentVersion()
+	c.JSON(http.StatusOK, gin.H{"message": "Reactions reordered"})
+}
+
+// validateReactionDefinition returns an error message for an invalid definition
+func validateReactionDefinition(definition *models.ReactionDefinition) string {
+	if !models.IsValidReactionKey(definition.Key) {
+		return "key must be 1-32 lowercase letters, digits or underscores"
+	}
+	if definition.Label == "" {
+		return "label cannot be empty"
+	}
+	if definition.Emoji == "" && definition.IconURL == "" {
+		return "emoji or icon_url is required"
+	}
+	if definition.IconURL != "" && extractFilenameFromURL(sanitizeImageURL(definition.IconURL)) == "" {
+		return "icon_url must be an uploaded image"
+	}
+	return ""
+}
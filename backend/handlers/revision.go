@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"shipshipship/database"
+	"shipshipship/models"
+	"shipshipship/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockEndPattern matches the end of HTML block elements, used to split content into diffable lines
+var blockEndPattern = regexp.MustCompile(`(?i)(</(p|h[1-6]|li|ul|ol|blockquote|pre|div|figure|table|tr)>|<br\s*/?>|<hr\s*/?>)`)
+
+// RevisionDiff describes the differences between two revisions of an event
+type RevisionDiff struct {
+	From        int                           `json:"from"`
+	To          int                           `json:"to"`
+	Fields      map[string]models.FieldChange `json:"fields"`
+	TagsAdded   []models.RevisionTag          `json:"tags_added"`
+	TagsRemoved []models.RevisionTag          `json:"tags_removed"`
+	Content     []utils.DiffOp                `json:"content"`
+}
+
+// GetEventRevisions returns the revision history of an event, newest first
+func GetEventRevisions(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	db := database.GetDB()
+	var event models.Event
+	if err := db.First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	var revisions []models.EventRevision
+	if err := db.Where("event_id = ?", eventID).Order("revision DESC").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetEventRevision returns a single revision of an event
+func GetEventRevision(c *gin.Context) {
+	eventID, revisionNumber, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	revision, err := models.GetEventRevision(database.GetDB(), uint(eventID), revisionNumber)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffEventRevisions compares two revisions of an event (?from=1&to=2)
+func DiffEventRevisions(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from revision"})
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to revision"})
+		return
+	}
+
+	db := database.GetDB()
+	fromRevision, err := models.GetEventRevision(db, uint(eventID), from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Revision %d not found", from)})
+		return
+	}
+	toRevision, err := models.GetEventRevision(db, uint(eventID), to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Revision %d not found", to)})
+		return
+	}
+
+	c.JSON(http.StatusOK, diffRevisions(fromRevision, toRevision))
+}
+
+// RestoreEventRevision restores an event to the state of an earlier revision.
+// The restore is applied as a regular update, so it creates a new revision.
+func RestoreEventRevision(c *gin.Context) {
+	eventID, revisionNumber, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	revision, err := models.GetEventRevision(database.GetDB(), uint(eventID), revisionNumber)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	media := []string{}
+	if revision.Media != "" {
+		if err := json.Unmarshal([]byte(revision.Media), &media); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Revision media is corrupted"})
+			return
+		}
+	}
+	if media == nil {
+		media = []string{} // "null" media must still be applied, not skipped
+	}
+	tagIDs := revision.TagIDs()
+
+	req := models.UpdateEventRequest{
+		Title:   &revision.Title,
+		TagIDs:  &tagIDs,
+		Media:   media,
+		Status:  &revision.Status,
+		Date:    &revision.Date,
+		Content: &revision.Content,
+	}
+
+	applyEventUpdate(c, eventID, req)
+}
+
+// parseRevisionParams parses the :id and :revision path parameters
+func parseRevisionParams(c *gin.Context) (uint64, int, bool) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return 0, 0, false
+	}
+
+	revisionNumber, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revisionNumber < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return 0, 0, false
+	}
+
+	return eventID, revisionNumber, true
+}
+
+// diffRevisions builds a field, tag and content diff between two revisions
+func diffRevisions(from, to *models.EventRevision) RevisionDiff {
+	diff := RevisionDiff{
+		From:        from.Revision,
+		To:          to.Revision,
+		Fields:      make(map[string]models.FieldChange),
+		TagsAdded:   []models.RevisionTag{},
+		TagsRemoved: []models.RevisionTag{},
+	}
+
+	if from.Title != to.Title {
+		diff.Fields["title"] = models.FieldChange{Before: from.Title, After: to.Title}
+	}
+	if from.Slug != to.Slug {
+		diff.Fields["slug"] = models.FieldChange{Before: from.Slug, After: to.Slug}
+	}
+	if from.Status != to.Status {
+		diff.Fields["status"] = models.FieldChange{Before: from.Status, After: to.Status}
+	}
+	if from.Date != to.Date {
+		diff.Fields["date"] = models.FieldChange{Before: from.Date, After: to.Date}
+	}
+	if from.Media != to.Media {
+		diff.Fields["media"] = models.FieldChange{Before: json.RawMessage(orEmptyArray(from.Media)), After: json.RawMessage(orEmptyArray(to.Media))}
+	}
+
+	fromTags := make(map[uint]bool)
+	for _, tag := range from.TagList() {
+		fromTags[tag.ID] = true
+	}
+	toTags := make(map[uint]bool)
+	for _, tag := range to.TagList() {
+		toTags[tag.ID] = true
+		if !fromTags[tag.ID] {
+			diff.TagsAdded = append(diff.TagsAdded, tag)
+		}
+	}
+	for _, tag := range from.TagList() {
+		if !toTags[tag.ID] {
+			diff.TagsRemoved = append(diff.TagsRemoved, tag)
+		}
+	}
+
+	diff.Content = utils.DiffLines(splitContentBlocks(from.Content), splitContentBlocks(to.Content))
+
+	return diff
+}
+
+// splitContentBlocks splits HTML content into one line per block element
+func splitContentBlocks(content string) []string {
+	content = blockEndPattern.ReplaceAllString(content, "$1\n")
+
+	blocks := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			blocks = append(blocks, line)
+		}
+	}
+	return blocks
+}
+
+// orEmptyArray returns "[]" for an empty JSON array string
+func orEmptyArray(value string) string {
+	if value == "" {
+		return "[]"
+	}
+	return value
+}
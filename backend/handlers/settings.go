@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
 
 	"github.com/gin-gonic/gin"
@@ -59,6 +60,8 @@ func UpdateSettings(c *gin.Context) {
 		return
 	}
 
+	before := *settings
+
 	// Update fields if provided
 	if req.Title != nil {
 		settings.Title = *req.Title
@@ -83,5 +86,8 @@ func UpdateSettings(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionUpdate, "settings", settings.ID, before, settings)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, settings)
 }
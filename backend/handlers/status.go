@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
 	"shipshipship/utils"
 
@@ -108,6 +109,8 @@ func CreateStatus(c *gin.Context) {
 			}
 			if err := db.Create(&mapping).Error; err != nil {
 				// Log error but don't fail the status creation
+				recordAudit(c, models.AuditActionCreate, "status", status.ID, nil, status)
+				middleware.BumpContentVersion()
 				c.JSON(http.StatusCreated, gin.H{
 					"status":  status,
 					"warning": "Status created but category mapping failed",
@@ -117,6 +120,9 @@ func CreateStatus(c *gin.Context) {
 		}
 	}
 
+	recordAudit(c, models.AuditActionCreate, "status", status.ID, nil, status)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusCreated, status)
 }
 
@@ -146,6 +152,7 @@ func UpdateStatus(c *gin.Context) {
 	}
 
 	originalName := status.DisplayName
+	before := status
 
 	// Apply changes
 	if req.DisplayName != nil {
@@ -208,6 +215,9 @@ func UpdateStatus(c *gin.Context) {
 		}
 	}
 
+	recordAudit(c, models.AuditActionUpdate, "status", status.ID, before, status)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, status)
 }
 
@@ -272,6 +282,9 @@ func DeleteStatus(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, "status", status.ID, status, nil)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{"message": "Status deleted"})
 }
 
@@ -291,6 +304,19 @@ func ReorderStatuses(c *gin.Context) {
 	}
 
 	db := database.GetDB()
+
+	// Snapshot current ordering for the audit log
+	var existing []models.EventStatusDefinition
+	db.Find(&existing)
+	names := make(map[uint]string)
+	before := make(map[string]int)
+	after := make(map[string]int)
+	for _, status := range existing {
+		names[status.ID] = status.DisplayName
+		before[status.DisplayName] = status.Order
+		after[status.DisplayName] = status.Order
+	}
+
 	for _, item := range req.Order {
 		if err := db.Model(&models.EventStatusDefinition{}).
 			Where("id = ?", item.ID).
@@ -300,5 +326,13 @@ func ReorderStatuses(c *gin.Context) {
 		}
 	}
 
+	for _, item := range req.Order {
+		if name, ok := names[item.ID]; ok {
+			after[name] = item.Order
+		}
+	}
+	recordAudit(c, models.AuditActionUpdate, "status_order", "all", before, after)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{"message": "Statuses reordered"})
 }
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
 
 	"github.com/gin-gonic/gin"
@@ -264,6 +265,11 @@ func UpdateStatusMapping(c *gin.Context) {
 	var mapping models.StatusCategoryMapping
 	err = db.Where("status_definition_id = ? AND theme_id = ?", statusID, settings.CurrentThemeID).First(&mapping).Error
 
+	previousCategory := ""
+	if err == nil {
+		previousCategory = mapping.CategoryID
+	}
+
 	if err == nil {
 		// Update existing mapping
 		mapping.CategoryID = req.CategoryID
@@ -284,6 +290,11 @@ func UpdateStatusMapping(c *gin.Context) {
 		}
 	}
 
+	recordAudit(c, models.AuditActionUpdate, "status_mapping", statusID,
+		gin.H{"status": status.DisplayName, "theme_id": settings.CurrentThemeID, "category_id": previousCategory},
+		gin.H{"status": status.DisplayName, "theme_id": settings.CurrentThemeID, "category_id": mapping.CategoryID})
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"mapping": mapping,
@@ -322,6 +333,12 @@ func DeleteStatusMapping(c *gin.Context) {
 		return
 	}
 
+	if result.RowsAffected > 0 {
+		recordAudit(c, models.AuditActionDelete, "status_mapping", statusID,
+			gin.H{"theme_id": settings.CurrentThemeID}, nil)
+	}
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Mapping deleted successfully",
@@ -386,17 +403,7 @@ func GetPublicEventsByCategory(c *gin.Context) {
 
 	// Populate events with sanitized URLs
 	for _, event := range events {
-		// Sanitize media URLs to convert localhost to relative URLs
-		var mediaURLs []string
-		if event.Media != "" {
-			json.Unmarshal([]byte(event.Media), &mediaURLs)
-			sanitizedURLs := SanitizeImageURLs(mediaURLs)
-			sanitizedJSON, _ := json.Marshal(sanitizedURLs)
-			event.Media = string(sanitizedJSON)
-		}
-
-		// Sanitize content URLs (HTML content with image tags)
-		event.Content = SanitizeHTMLContent(event.Content)
+		prepareEventResponse(&event)
 
 		categoryID, exists := statusCategoryMap[string(event.Status)]
 		if exists {
@@ -600,6 +607,16 @@ func UpdateThemeSettings(c *gin.Context) {
 		}
 	}
 
+	// Snapshot stored values for the audit log
+	var storedValues []models.ThemeSettingValue
+	db.Where("theme_id = ?", settings.CurrentThemeID).Find(&storedValues)
+	before := make(map[string]string)
+	after := make(map[string]string)
+	for _, sv := range storedValues {
+		before[sv.SettingID] = sv.Value
+		after[sv.SettingID] = sv.Value
+	}
+
 	// Update each setting value
 	for settingID, value := range req {
 		// Validate that this setting exists in the theme
@@ -655,8 +672,12 @@ func UpdateThemeSettings(c *gin.Context) {
 				return
 			}
 		}
+		after[settingID] = valueStr
 	}
 
+	recordAudit(c, models.AuditActionUpdate, "theme_settings", settings.CurrentThemeID, before, after)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Settings updated successfully",
@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
+	"shipshipship/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // GetTags returns all tags
@@ -73,6 +77,8 @@ func CreateTag(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionCreate, "tag", tag.ID, nil, tag)
+
 	c.JSON(http.StatusCreated, tag)
 }
 
@@ -98,6 +104,8 @@ func UpdateTag(c *gin.Context) {
 		return
 	}
 
+	before := tag
+
 	// Update fields if provided
 	if req.Name != nil {
 		tag.Name = *req.Name
@@ -121,6 +129,14 @@ func UpdateTag(c *gin.Context) {
 		return
 	}
 
+	// Tag names are part of the search index of their events
+	if tag.Name != before.Name {
+		reindexTaggedEvents(db, taggedEventIDs(db, tag.ID))
+	}
+
+	recordAudit(c, models.AuditActionUpdate, "tag", tag.ID, before, tag)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, tag)
 }
 
@@ -148,6 +164,8 @@ func DeleteTag(c *gin.Context) {
 		return
 	}
 
+	eventIDs := taggedEventIDs(db, tag.ID)
+
 	// Start a transaction to ensure atomicity
 	tx := db.Begin()
 	defer func() {
@@ -176,6 +194,11 @@ func DeleteTag(c *gin.Context) {
 		return
 	}
 
+	reindexTaggedEvents(db, eventIDs)
+
+	recordAudit(c, models.AuditActionDelete, "tag", tag.ID, tag, nil)
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
 }
 
@@ -210,3 +233,16 @@ func GetTagUsage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, tagUsage)
 }
+
+// taggedEventIDs returns the IDs of the events that have a tag
+func taggedEventIDs(db *gorm.DB, tagID uint) []uint {
+	var eventIDs []uint
+	db.Table("event_tags").Where("tag_id = ?", tagID).Pluck("event_id", &eventIDs)
+	return eventIDs
+}
+
+func reindexTaggedEvents(db *gorm.DB, eventIDs []uint) {
+	if err := services.IndexEvents(db, eventIDs); err != nil {
+		fmt.Printf("Warning: Failed to update search index after tag change: %v\n", err)
+	}
+}
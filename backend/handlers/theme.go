@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"shipshipship/constants"
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
 	"strings"
 	"time"
@@ -110,11 +111,14 @@ func ApplyTheme(c *gin.Context) {
 	settings, err := models.GetOrCreateSettings(db)
 	isUpdate := false
 	oldVersion := ""
+	previousTheme := gin.H{}
 
 	if err != nil {
 		// Theme was applied but we couldn't update settings - log but don't fail
 		fmt.Printf("Warning: Theme applied but couldn't update settings: %v\n", err)
 	} else {
+		previousTheme = gin.H{"theme_id": settings.CurrentThemeID, "version": settings.CurrentThemeVersion}
+
 		// Check if we're updating an existing theme
 		if settings.CurrentThemeID == req.ThemeID && settings.CurrentThemeVersion != "" {
 			isUpdate = true
@@ -155,11 +159,14 @@ func ApplyTheme(c *gin.Context) {
 	// Clean up backup after successful application
 	os.RemoveAll(backupDir)
 
+	recordAudit(c, models.AuditActionApply, "theme", req.ThemeID, previousTheme, gin.H{"theme_id": req.ThemeID, "version": req.ThemeVersion})
+
 	message := "Theme applied successfully"
 	if isUpdate {
 		message = fmt.Sprintf("Theme updated successfully from %s to %s", oldVersion, req.ThemeVersion)
 	}
 
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, ApplyThemeResponse{
 		Success:    true,
 		Message:    message,
@@ -288,6 +295,11 @@ func RedownloadTheme(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionApply, "theme", themeRecord.ID,
+		gin.H{"theme_id": settings.CurrentThemeID, "version": settings.CurrentThemeVersion},
+		gin.H{"theme_id": themeRecord.ID, "version": themeVersion, "redownload": true})
+
+	middleware.BumpContentVersion()
 	c.JSON(http.StatusOK, gin.H{
 		"success":   true,
 		"message":   "Theme redownloaded successfully",
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetUsers returns all admin accounts (owner only)
+func GetUsers(c *gin.Context) {
+	var users []models.User
+
+	db := database.GetDB()
+	if err := db.Order("username ASC").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUser returns a single admin account by ID (owner only)
+func GetUser(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	db := database.GetDB()
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// CreateUser creates a new admin account (owner only)
+func CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username cannot be empty"})
+		return
+	}
+
+	if !models.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	db := database.GetDB()
+
+	// Usernames are unique among live accounts regardless of case
+	var count int64
+	db.Model(&models.User{}).Where("LOWER(username) = ?", strings.ToLower(username)).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User with this username already exists"})
+		return
+	}
+
+	user := models.User{
+		Username: username,
+		Email:    strings.TrimSpace(req.Email),
+		Role:     req.Role,
+	}
+	if err := user.SetPassword(req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if err := db.Create(&user).Error; err != nil {
+		// A concurrent request created the same username first
+		if models.IsDuplicateError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "User with this username already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, "user", user.ID, nil, user)
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUser updates an admin account's email, password or role (owner only)
+func UpdateUser(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	before := user
+
+	// Update fields if provided
+	if req.Email != nil {
+		user.Email = strings.TrimSpace(*req.Email)
+	}
+	if req.Password != nil {
+		if len(*req.Password) < 8 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters"})
+			return
+		}
+		if err := user.SetPassword(*req.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+	}
+	if req.Role != nil {
+		if !models.IsValidRole(*req.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+			return
+		}
+
+		// Never demote the last owner, otherwise nobody can manage users anymore
+		if user.Role == models.RoleOwner && *req.Role != models.RoleOwner {
+			lastOwner, err := isLastOwner(db)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check owners"})
+				return
+			}
+			if lastOwner {
+				c.JSON(http.StatusConflict, gin.H{"error": "Cannot change the role of the last owner"})
+				return
+			}
+		}
+		user.Role = *req.Role
+	}
+
+	if err := db.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, "user", user.ID, before, user)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser deletes an admin account (owner only)
+func DeleteUser(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	db := database.GetDB()
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Prevent users from locking themselves out
+	if currentID, exists := c.Get("user_id"); exists && currentID == user.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "You cannot delete your own account"})
+		return
+	}
+
+	if user.Role == models.RoleOwner {
+		lastOwner, err := isLastOwner(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check owners"})
+			return
+		}
+		if lastOwner {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last owner"})
+			return
+		}
+	}
+
+	if err := db.Delete(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, "user", user.ID, user, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+// isLastOwner reports whether only one owner account remains. When the owners cannot
+// be counted it answers true, so the last owner is never removed by accident.
+func isLastOwner(db *gorm.DB) (bool, error) {
+	count, err := models.CountOwners(db)
+	if err != nil {
+		return true, err
+	}
+	return count <= 1, nil
+}
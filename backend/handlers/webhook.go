@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+	"shipshipship/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhooks returns all configured webhooks
+func GetWebhooks(c *gin.Context) {
+	var webhooks []models.Webhook
+
+	db := database.GetDB()
+	if err := db.Order("created_at ASC").Find(&webhooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	// Secrets are only shown when created or rotated
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks":    webhooks,
+		"event_types": models.WebhookEventTypes,
+	})
+}
+
+// GetWebhook returns a single webhook
+func GetWebhook(c *gin.Context) {
+	webhook, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	webhook.Secret = ""
+	c.JSON(http.StatusOK, webhook)
+}
+
+// CreateWebhook creates a new webhook; the signing secret is returned only in this response
+func CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateWebhookURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret := strings.TrimSpace(req.Secret)
+	if secret == "" {
+		generated, err := services.GenerateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+			return
+		}
+		secret = generated
+	}
+
+	webhook := models.Webhook{
+		Name:   strings.TrimSpace(req.Name),
+		URL:    strings.TrimSpace(req.URL),
+		Secret: secret,
+		Active: true,
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	webhook.SetEventTypes(req.EventTypes)
+
+	db := database.GetDB()
+	if err := db.Create(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, "webhook", webhook.ID, nil, maskedWebhook(webhook))
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// UpdateWebhook updates a webhook's URL, secret, subscriptions or active flag
+func UpdateWebhook(c *gin.Context) {
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	before := maskedWebhook(*webhook)
+	secretChanged := false
+
+	if req.Name != nil {
+		webhook.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		webhook.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.EventTypes != nil {
+		if err := validateWebhookEventTypes(*req.EventTypes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		webhook.SetEventTypes(*req.EventTypes)
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if req.Secret != nil && strings.TrimSpace(*req.Secret) != "" {
+		webhook.Secret = strings.TrimSpace(*req.Secret)
+		secretChanged = true
+	}
+	if req.RotateSecret {
+		generated, err := services.GenerateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+			return
+		}
+		webhook.Secret = generated
+		secretChanged = true
+	}
+
+	db := database.GetDB()
+	if err := db.Save(webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+
+	after := maskedWebhook(*webhook)
+	if secretChanged {
+		after.Secret = "******** (changed)"
+	}
+	recordAudit(c, models.AuditActionUpdate, "webhook", webhook.ID, before, after)
+
+	// Only return the secret when it was just changed
+	if !secretChanged {
+		webhook.Secret = ""
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func DeleteWebhook(c *gin.Context) {
+	webhook, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+	if err := db.Delete(webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	if err := db.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		fmt.Printf("Warning: Failed to delete deliveries for webhook %d: %v\n", webhook.ID, err)
+	}
+
+	recordAudit(c, models.AuditActionDelete, "webhook", webhook.ID, maskedWebhook(*webhook), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// TestWebhook sends a test delivery synchronously and returns its result
+func TestWebhook(c *gin.Context) {
+	webhook, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+	delivery, err := services.QueueWebhookDelivery(db, webhook, models.WebhookTest, gin.H{
+		"webhook_id": webhook.ID,
+		"message":    "This is a test delivery",
+		"sent_by":    c.GetString("username"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue test delivery"})
+		return
+	}
+
+	result := services.AttemptWebhookDelivery(db, delivery.ID)
+	if result == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test delivery"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetWebhookDeliveries returns the paginated delivery log of a webhook (?status= to filter)
+func GetWebhookDeliveries(c *gin.Context) {
+	webhook, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	page, limit := parsePagination(c, 20)
+
+	deliveries, total, err := models.GetWebhookDeliveries(database.GetDB(), webhook.ID, c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries":  deliveries,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RetryWebhookDelivery re-sends a delivery immediately, including ones that already failed
+func RetryWebhookDelivery(c *gin.Context) {
+	webhook, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	db := database.GetDB()
+	var delivery models.WebhookDelivery
+	if err := db.Where("id = ? AND webhook_id = ?", deliveryID, webhook.ID).First(&delivery).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+
+	if delivery.Status == models.WebhookDeliverySending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Delivery is currently being sent"})
+		return
+	}
+
+	// A manual retry starts a fresh round of attempts
+	now := time.Now()
+	if err := db.Model(&delivery).Updates(map[string]interface{}{
+		"status":          models.WebhookDeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset delivery"})
+		return
+	}
+
+	result := services.AttemptWebhookDelivery(db, delivery.ID)
+	if result == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Delivery is currently being sent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// loadWebhook loads the webhook referenced by the :id path parameter
+func loadWebhook(c *gin.Context) (*models.Webhook, bool) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	var webhook models.Webhook
+	if err := database.GetDB().First(&webhook, webhookID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+
+	return &webhook, true
+}
+
+// validateWebhookURL requires an absolute http(s) URL
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// validateWebhookEventTypes requires at least one known event type
+func validateWebhookEventTypes(types []string) error {
+	if len(types) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	for _, t := range types {
+		if !models.IsValidWebhookEventType(t) {
+			return fmt.Errorf("unknown event type: %s", t)
+		}
+	}
+	return nil
+}
+
+// maskedWebhook returns a copy of the webhook with its secret hidden, for the audit log
+func maskedWebhook(webhook models.Webhook) models.Webhook {
+	webhook.Secret = maskSecret(webhook.Secret)
+	return webhook
+}
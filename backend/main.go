@@ -128,12 +128,51 @@ func main() {
 		log.Printf("The system will continue to run. You can manually install a theme from the admin panel at /admin/customization/theme")
 	}
 
-	// Start cleanup service for orphaned files
 	db := database.GetDB()
+
+	// Identify stored reactions and votes by visitor rather than raw IP address
+	if err := models.MigrateVisitorIdentities(db, middleware.HashIP); err != nil {
+		log.Printf("Warning: Failed to migrate visitor identities: %v", err)
+	}
+	if err := models.HashStoredIPAddresses(db, middleware.HashIP); err != nil {
+		log.Printf("Warning: Failed to hash stored IP addresses: %v", err)
+	}
+
+	// Create or refresh the full-text search index for events
+	if err := services.SetupEventSearch(db); err != nil {
+		log.Printf("Warning: Event search is disabled: %v", err)
+	}
+
+	// Start cleanup service for orphaned files
 	cleanupService := services.NewCleanupService(db, "./data/uploads")
 	cleanupService.Start()
 	defer cleanupService.Stop()
 
+	// Start scheduler for timed publish/unpublish of events
+	schedulerService := services.NewSchedulerService(db)
+	schedulerService.Start()
+	defer schedulerService.Stop()
+
+	// Start retry loop for webhook deliveries
+	webhookService := services.NewWebhookService(db)
+	webhookService.Start()
+	defer webhookService.Stop()
+
+	// Start worker pool for queued newsletter emails
+	emailQueueService := services.NewEmailQueueService(db)
+	emailQueueService.Start()
+	defer emailQueueService.Stop()
+
+	// Start bounce service (reads BOUNCE_MAILDIR when set)
+	bounceService := services.NewBounceService(db)
+	bounceService.Start()
+	defer bounceService.Stop()
+
+	// Start retention job for visitor identities on reactions and votes
+	privacyService := services.NewPrivacyService(db)
+	privacyService.Start()
+	defer privacyService.Stop()
+
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -152,25 +191,29 @@ func main() {
 	// Public routes
 	api := r.Group("/api")
 	{
-		api.GET("/events", handlers.GetEvents)
-		api.GET("/events/:id", handlers.GetEvent)
-		api.GET("/events/slug/:slug", handlers.GetEventBySlug)
+		// Routes that depend on the visitor's own reactions and votes
+		visitor := api.Group("", middleware.Visitor())
+
+		visitor.GET("/events", middleware.PublicCache(true), handlers.GetEvents)
+		api.GET("/events/search", handlers.SearchEvents)
+		visitor.GET("/events/:id", handlers.GetEvent)
+		visitor.GET("/events/slug/:slug", handlers.GetEventBySlug)
 
 		// Reaction routes (new system)
-		api.POST("/events/:id/reactions", handlers.AddOrRemoveReaction)
-		api.GET("/events/:id/reactions", handlers.GetEventReactions)
-		api.GET("/events/:id/reactions/me", handlers.GetMyReactions)
+		visitor.POST("/events/:id/reactions", middleware.ReactionRateLimit(), handlers.AddOrRemoveReaction)
+		visitor.GET("/events/:id/reactions", handlers.GetEventReactions)
+		visitor.GET("/events/:id/reactions/me", handlers.GetMyReactions)
 		api.GET("/events/reactions/counts", handlers.GetAllEventReactionsCount)
 		api.GET("/reactions/types", handlers.GetReactionTypes)
 
 		// Legacy vote routes (keep for backward compatibility)
-		api.POST("/events/:id/vote", handlers.VoteEvent)
-		api.GET("/events/:id/vote-status", handlers.CheckVoteStatus)
+		visitor.POST("/events/:id/vote", middleware.ReactionRateLimit(), handlers.VoteEvent)
+		visitor.GET("/events/:id/vote-status", handlers.CheckVoteStatus)
 
 		api.POST("/feedback", middleware.FeedbackRateLimit(), handlers.SubmitFeedback)
 		api.POST("/auth/login", handlers.Login)
 		api.GET("/auth/demo-mode", handlers.CheckDemoMode)
-		api.GET("/settings", handlers.GetSettings)
+		api.GET("/settings", middleware.PublicCache(false), handlers.GetSettings)
 
 		// Tag routes (public)
 		api.GET("/tags", handlers.GetTags)
@@ -178,8 +221,15 @@ func main() {
 		api.GET("/statuses", handlers.GetStatuses)
 
 		// Newsletter routes
-		api.POST("/newsletter/subscribe", handlers.SubscribeToNewsletter)
+		api.POST("/newsletter/subscribe", middleware.SubscribeRateLimit(), handlers.SubscribeToNewsletter)
 		api.POST("/newsletter/unsubscribe", handlers.UnsubscribeFromNewsletter)
+		api.POST("/newsletter/unsubscribe/one-click", handlers.OneClickUnsubscribe)
+		api.POST("/newsletter/confirm", handlers.ConfirmNewsletterSubscription)
+		api.GET("/newsletter/preferences", handlers.GetSubscriberPreferences)
+		api.PUT("/newsletter/preferences", handlers.UpdateSubscriberPreferences)
+		api.GET("/newsletter/track/open", handlers.TrackEmailOpen)
+		api.GET("/newsletter/track/click", handlers.TrackEmailClick)
+		api.POST("/newsletter/bounces/:source", handlers.ReceiveBounceWebhook)
 		api.GET("/newsletter/status", handlers.CheckSubscriptionStatus)
 
 		// Theme routes (public read access for admin interface)
@@ -187,80 +237,125 @@ func main() {
 	}
 
 	// Protected admin routes
+	// Viewers get read access; editor and owner groups add write access on top
 	admin := api.Group("/admin")
 	admin.Use(middleware.AuthMiddleware())
+	editor := admin.Group("", middleware.RequireRole(models.RoleEditor))
+	owner := admin.Group("", middleware.RequireRole(models.RoleOwner))
 	{
 		admin.GET("/validate", handlers.ValidateToken)
 		admin.GET("/events", handlers.GetAllEvents)
-		admin.POST("/events", handlers.CreateEvent)
-		admin.PUT("/events/:id", handlers.UpdateEvent)
-		admin.DELETE("/events/:id", handlers.DeleteEvent)
-		admin.PUT("/settings", handlers.UpdateSettings)
-		admin.POST("/upload/image", handlers.UploadImage)
+		admin.GET("/events/search", handlers.SearchAllEvents)
+		editor.POST("/events", handlers.CreateEvent)
+		editor.PUT("/events/:id", handlers.UpdateEvent)
+		editor.DELETE("/events/:id", handlers.DeleteEvent)
+		owner.PUT("/settings", handlers.UpdateSettings)
+		editor.POST("/upload/image", handlers.UploadImage)
+
+		// User admin routes
+		owner.GET("/users", handlers.GetUsers)
+		owner.GET("/users/:id", handlers.GetUser)
+		owner.POST("/users", handlers.CreateUser)
+		owner.PUT("/users/:id", handlers.UpdateUser)
+		owner.DELETE("/users/:id", handlers.DeleteUser)
+
+		// Audit log routes
+		owner.GET("/audit", handlers.GetAuditLog)
+
+		// Webhook routes
+		owner.GET("/webhooks", handlers.GetWebhooks)
+		owner.POST("/webhooks", handlers.CreateWebhook)
+		owner.GET("/webhooks/:id", handlers.GetWebhook)
+		owner.PUT("/webhooks/:id", handlers.UpdateWebhook)
+		owner.DELETE("/webhooks/:id", handlers.DeleteWebhook)
+		owner.POST("/webhooks/:id/test", handlers.TestWebhook)
+		owner.GET("/webhooks/:id/deliveries", handlers.GetWebhookDeliveries)
+		owner.POST("/webhooks/:id/deliveries/:deliveryId/retry", handlers.RetryWebhookDelivery)
 
 		// Tag admin routes
 		admin.GET("/tags", handlers.GetTags)
 		admin.GET("/tags/usage", handlers.GetTagUsage)
 		admin.GET("/tags/:id", handlers.GetTag)
-		admin.POST("/tags", handlers.CreateTag)
-		admin.PUT("/tags/:id", handlers.UpdateTag)
-		admin.DELETE("/tags/:id", handlers.DeleteTag)
+		editor.POST("/tags", handlers.CreateTag)
+		editor.PUT("/tags/:id", handlers.UpdateTag)
+		editor.DELETE("/tags/:id", handlers.DeleteTag)
 		// Status admin routes
 		admin.GET("/statuses", handlers.GetStatuses)
 		admin.GET("/statuses/:id", handlers.GetStatus)
-		admin.POST("/statuses", handlers.CreateStatus)
-		admin.PUT("/statuses/:id", handlers.UpdateStatus)
-		admin.DELETE("/statuses/:id", handlers.DeleteStatus)
-		admin.POST("/statuses/reorder", handlers.ReorderStatuses)
+		editor.POST("/statuses", handlers.CreateStatus)
+		editor.PUT("/statuses/:id", handlers.UpdateStatus)
+		editor.DELETE("/statuses/:id", handlers.DeleteStatus)
+		editor.POST("/statuses/reorder", handlers.ReorderStatuses)
+
+		// Reaction admin routes
+		admin.GET("/reactions", handlers.GetReactionDefinitions)
+		editor.POST("/reactions", handlers.CreateReactionDefinition)
+		editor.PUT("/reactions/:id", handlers.UpdateReactionDefinition)
+		editor.DELETE("/reactions/:id", handlers.DeleteReactionDefinition)
+		editor.POST("/reactions/reorder", handlers.ReorderReactionDefinitions)
 
 		// Mail settings routes
-		admin.GET("/settings/mail", handlers.GetMailSettings)
-		admin.POST("/settings/mail", handlers.UpdateMailSettings)
-		admin.POST("/settings/mail/test", handlers.TestMailSettings)
+		owner.GET("/settings/mail", handlers.GetMailSettings)
+		owner.POST("/settings/mail", handlers.UpdateMailSettings)
+		owner.POST("/settings/mail/test", handlers.TestMailSettings)
 
 		// Newsletter admin routes
 		admin.GET("/newsletter/stats", handlers.GetNewsletterStats)
-		admin.GET("/newsletter/subscribers", handlers.GetNewsletterSubscribers)
-		admin.GET("/newsletter/subscribers/paginated", handlers.GetNewsletterSubscribersPaginated)
-		admin.DELETE("/newsletter/subscribers/:email", handlers.DeleteNewsletterSubscriber)
+		editor.GET("/newsletter/subscribers", handlers.GetNewsletterSubscribers)
+		editor.GET("/newsletter/subscribers/paginated", handlers.GetNewsletterSubscribersPaginated)
+		editor.GET("/newsletter/subscribers/export", handlers.ExportNewsletterSubscribers)
+		editor.POST("/newsletter/subscribers/import", handlers.ImportNewsletterSubscribers)
+		editor.DELETE("/newsletter/subscribers/:email", handlers.DeleteNewsletterSubscriber)
+		editor.GET("/newsletter/suppressions", handlers.GetEmailSuppressions)
+		editor.POST("/newsletter/suppressions", handlers.CreateEmailSuppression)
+		editor.DELETE("/newsletter/suppressions/:id", handlers.DeleteEmailSuppression)
 		admin.GET("/newsletter/history", handlers.GetNewsletterHistory)
+		admin.GET("/newsletter/campaigns", handlers.GetCampaigns)
+		admin.GET("/newsletter/campaigns/:id", handlers.GetCampaign)
+		admin.GET("/newsletter/campaigns/:id/stream", handlers.StreamCampaignProgress)
+		editor.GET("/newsletter/campaigns/:id/jobs", handlers.GetCampaignJobs)
+		editor.POST("/newsletter/campaigns/:id/retry", handlers.RetryCampaign)
 		admin.GET("/newsletter/templates", handlers.GetEmailTemplates)
-		admin.PUT("/newsletter/templates", handlers.UpdateEmailTemplates)
+		editor.PUT("/newsletter/templates", handlers.UpdateEmailTemplates)
 		admin.GET("/newsletter/automation", handlers.GetNewsletterAutomationSettings)
-		admin.PUT("/newsletter/automation", handlers.UpdateNewsletterAutomationSettings)
+		editor.PUT("/newsletter/automation", handlers.UpdateNewsletterAutomationSettings)
 
 		// Event publishing routes
 		admin.GET("/events/:id/publish", handlers.GetEventPublishStatus)
-		admin.PUT("/events/:id/publish", handlers.UpdateEventPublicStatus)
+		editor.PUT("/events/:id/publish", handlers.UpdateEventPublicStatus)
 		admin.GET("/events/:id/newsletter/preview", handlers.GetEventNewsletterPreview)
-		admin.POST("/events/:id/newsletter/send", handlers.SendEventNewsletter)
+		editor.POST("/events/:id/newsletter/send", handlers.SendEventNewsletter)
 		admin.GET("/events/:id/newsletter/history", handlers.GetEventEmailHistory)
+		admin.GET("/events/:id/revisions", handlers.GetEventRevisions)
+		admin.GET("/events/:id/revisions/diff", handlers.DiffEventRevisions)
+		admin.GET("/events/:id/revisions/:revision", handlers.GetEventRevision)
+		editor.POST("/events/:id/revisions/:revision/restore", handlers.RestoreEventRevision)
 
 		// Theme admin routes
-		admin.POST("/themes/apply", handlers.ApplyTheme)
-		admin.POST("/themes/redownload", handlers.RedownloadTheme)
+		owner.POST("/themes/apply", handlers.ApplyTheme)
+		owner.POST("/themes/redownload", handlers.RedownloadTheme)
 		admin.GET("/themes/current", handlers.GetCurrentTheme)
 		admin.GET("/themes/info", handlers.GetThemeInfo)
 
 		// Theme manifest and status mapping routes
 		admin.GET("/theme/manifest", handlers.GetThemeManifest)
 		admin.GET("/status-mappings", handlers.GetStatusMappings)
-		admin.PUT("/status-mappings/:statusId", handlers.UpdateStatusMapping)
-		admin.DELETE("/status-mappings/:statusId", handlers.DeleteStatusMapping)
+		owner.PUT("/status-mappings/:statusId", handlers.UpdateStatusMapping)
+		owner.DELETE("/status-mappings/:statusId", handlers.DeleteStatusMapping)
 
 		// Theme settings routes
 		admin.GET("/theme/settings", handlers.GetThemeSettings)
-		admin.PUT("/theme/settings", handlers.UpdateThemeSettings)
+		owner.PUT("/theme/settings", handlers.UpdateThemeSettings)
 
 		// Migration route (one-time use)
-		admin.POST("/migrate/votes-to-reactions", handlers.MigrateVotesToReactions)
+		owner.POST("/migrate/votes-to-reactions", handlers.MigrateVotesToReactions)
 	}
 
 	// Public events by category endpoint
-	api.GET("/events/by-category", handlers.GetPublicEventsByCategory)
+	api.GET("/events/by-category", middleware.PublicCache(false), handlers.GetPublicEventsByCategory)
 
 	// Public theme settings endpoint
-	api.GET("/theme/settings", handlers.GetPublicThemeSettings)
+	api.GET("/theme/settings", middleware.PublicCache(false), handlers.GetPublicThemeSettings)
 	api.GET("/theme/status-mappings", handlers.GetPublicStatusMappings)
 
 	// Public file serving route
@@ -328,6 +423,17 @@ func main() {
 		c.File(filepath.Join(getAdminBuildPath(), "favicon.ico"))
 	})
 
+	// Newsletter confirmation link target (double opt-in)
+	r.GET("/newsletter/confirm", handlers.ConfirmNewsletterSubscription)
+
+	// Subscriber preference center linked from newsletters
+	r.GET("/newsletter/preferences", handlers.ServePreferenceCenter)
+	r.POST("/newsletter/preferences", handlers.ServePreferenceCenter)
+
+	// Public feeds of the changelog (?category= or ?tag= for filtered variants)
+	r.GET("/feed.rss", handlers.GetRSSFeed)
+	r.GET("/feed.atom", handlers.GetAtomFeed)
+
 	// Public changelog routes - serve theme if available
 	r.GET("/", func(c *gin.Context) {
 		// Check if theme exists
@@ -0,0 +1,48 @@
+// Package markdown renders event content to HTML. Content is Markdown
+// with GitHub extensions; content written by the rich text editor is already HTML
+// and is only sanitized.
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/net/html"
+)
+
+// Raw HTML in Markdown is rendered as written; the output is sanitized afterwards
+var renderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+// Content starting with one of these elements is HTML from the editor
+var htmlContentRegex = regexp.MustCompile(`(?i)^<(p|h[1-6]|ul|ol|div|blockquote|pre|table|figure|img|hr|br)[\s/>]`)
+
+// ToHTML converts content to sanitized HTML. Fenced code blocks get a language-*
+// class, headings an id to link to.
+func ToHTML(content string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	if IsHTML(content) {
+		return SanitizeHTML(content)
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(unescapeCode(content)), &buf); err != nil {
+		return html.EscapeString(content)
+	}
+	return SanitizeHTML(buf.String())
+}
+
+// IsHTML reports whether content was written as HTML rather than Markdown
+func IsHTML(content string) bool {
+	return htmlContentRegex.MatchString(strings.TrimSpace(content))
+}
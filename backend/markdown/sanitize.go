@@ -0,0 +1,216 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/net/html"
+)
+
+// Hosts whose players may be embedded with an iframe
+var embedSrcRegex = regexp.MustCompile(`^https://(www\.youtube\.com/embed/|www\.youtube-nocookie\.com/embed/|player\.vimeo\.com/video/|www\.loom\.com/embed/)[\w\-./?=&%]*$`)
+
+// policy allows the markup written by the editor and produced by the Markdown renderer.
+// Everything else is removed: scripts, styles, event handlers, forms, unknown iframes
+// and URLs other than http(s), mailto and relative ones.
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Globally()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w\-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("target").Matching(regexp.MustCompile(`^_blank$`)).OnElements("a")
+
+	// Task list checkboxes
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	// Tables from the editor carry column widths
+	p.AllowElements("colgroup", "col")
+	p.AllowAttrs("colspan", "rowspan", "colwidth").Matching(bluemonday.Integer).OnElements("td", "th")
+	p.AllowStyles("width", "min-width").Matching(regexp.MustCompile(`^\d+(\.\d+)?(px|%)$`)).OnElements("table", "col")
+
+	// Video embeds
+	p.AllowAttrs("src").Matching(embedSrcRegex).OnElements("iframe")
+	p.AllowAttrs("width", "height", "frameborder").Matching(bluemonday.Integer).OnElements("iframe")
+	p.AllowAttrs("allowfullscreen", "title").OnElements("iframe")
+	return p
+}
+
+// SanitizeHTML removes everything from HTML that is not on the allowlist
+func SanitizeHTML(content string) string {
+	return policy.Sanitize(content)
+}
+
+// Sanitize removes unsafe markup from event content. HTML content is cleaned as a
+// whole; Markdown keeps its text as written and only the HTML tags in it are cleaned.
+// Code spans and code blocks in Markdown are entity-escaped, since some clients show
+// raw content as HTML. Sanitizing content twice changes nothing.
+func Sanitize(content string) string {
+	if IsHTML(content) {
+		return SanitizeHTML(content)
+	}
+	if !strings.ContainsAny(content, "<>&") {
+		return content
+	}
+	return sanitizeInlineHTML(content)
+}
+
+// Elements whose content the browser does not parse as markup; it is dropped with them
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true, "xmp": true,
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true, "plaintext": true,
+}
+
+// Markdown autolinks such as <https://example.com>, kept as written
+var autolinkRegex = regexp.MustCompile(`^<((https?|mailto):[^\s<>]*|[\w.+\-]+@[\w\-]+(\.[\w\-]+)+)>$`)
+
+// sanitizeInlineHTML cleans each tag in Markdown on its own and leaves the text in
+// between untouched, so Markdown syntax such as "> quote" is not escaped. Code spans
+// and code blocks keep their text, entity-escaped: they are shown as text, not parsed
+// as markup.
+func sanitizeInlineHTML(content string) string {
+	var b strings.Builder
+	rawText := ""
+	open := map[string]int{} // kept start tags not closed yet
+
+	sanitizeText := func(text string) {
+		tokenizer := html.NewTokenizer(strings.NewReader(text))
+		for {
+			tokenType := tokenizer.Next()
+			if tokenType == html.ErrorToken {
+				return
+			}
+			raw := string(tokenizer.Raw())
+
+			switch tokenType {
+			case html.TextToken:
+				if rawText == "" {
+					b.WriteString(raw)
+				}
+			case html.StartTagToken, html.SelfClosingTagToken:
+				if autolinkRegex.MatchString(raw) {
+					b.WriteString(raw)
+					continue
+				}
+				name, _ := tokenizer.TagName()
+				tag := string(name)
+				if tokenType == html.StartTagToken && rawTextElements[tag] {
+					rawText = tag
+				}
+				sanitized := policy.Sanitize(raw)
+				if tokenType == html.StartTagToken && sanitized != "" {
+					open[tag]++
+				}
+				b.WriteString(sanitized)
+			case html.EndTagToken:
+				name, _ := tokenizer.TagName()
+				tag := string(name)
+				if tag == rawText {
+					rawText = ""
+				}
+				// The end tag of a removed element goes with it
+				if open[tag] > 0 {
+					open[tag]--
+					b.WriteString(policy.Sanitize(raw))
+				}
+			}
+		}
+	}
+
+	start := 0
+	for _, code := range codeSegments(content) {
+		sanitizeText(content[start:code.Start])
+		if rawText == "" {
+			b.WriteString(escapeCode(content[code.Start:code.Stop]))
+		}
+		start = code.Stop
+	}
+	sanitizeText(content[start:])
+	return b.String()
+}
+
+// codeSegments returns the byte ranges of the code spans and code blocks in Markdown,
+// in order, as the renderer parses them
+func codeSegments(content string) []text.Segment {
+	source := []byte(content)
+	document := renderer.Parser().Parse(text.NewReader(source))
+
+	var segments []text.Segment
+	ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := node.(type) {
+		case *ast.CodeSpan:
+			first, last := node.FirstChild(), node.LastChild()
+			if first == nil {
+				return ast.WalkSkipChildren, nil
+			}
+			firstText, ok1 := first.(*ast.Text)
+			lastText, ok2 := last.(*ast.Text)
+			if ok1 && ok2 {
+				segments = append(segments, text.NewSegment(firstText.Segment.Start, lastText.Segment.Stop))
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := node.Lines()
+			if lines.Len() > 0 {
+				segments = append(segments, text.NewSegment(lines.At(0).Start, lines.At(lines.Len()-1).Stop))
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return segments
+}
+
+// An entity already escaped in stored code
+var codeEntityRegex = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+
+// escapeCode escapes <, > and & in code as HTML entities. Entities already in it are
+// kept, so escaping code twice changes nothing.
+func escapeCode(code string) string {
+	var b strings.Builder
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '&':
+			if codeEntityRegex.MatchString(code[i:]) {
+				b.WriteByte('&')
+			} else {
+				b.WriteString("&amp;")
+			}
+		default:
+			b.WriteByte(code[i])
+		}
+	}
+	return b.String()
+}
+
+// Only the entities escapeCode writes are reverted; others could change where a code
+// span ends
+var codeUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// unescapeCode reverts the escaping of the code spans and code blocks in stored
+// Markdown, so they render with the text as written
+func unescapeCode(content string) string {
+	if !strings.Contains(content, "&") {
+		return content
+	}
+	var b strings.Builder
+	start := 0
+	for _, code := range codeSegments(content) {
+		b.WriteString(content[start:code.Start])
+		b.WriteString(codeUnescaper.Replace(content[code.Start:code.Stop]))
+		start = code.Stop
+	}
+	b.WriteString(content[start:])
+	return b.String()
+}
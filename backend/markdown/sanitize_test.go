@@ -0,0 +1,170 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// want is the stored content after Sanitize; empty means only the checks below apply
+		want string
+		// forbidden must appear neither in the stored content nor in the rendered HTML
+		forbidden []string
+		// rendered must appear in the rendered HTML, notRendered must not
+		rendered    []string
+		notRendered []string
+	}{
+		{
+			name:      "script in Markdown",
+			content:   "Hello <script>alert(1)</script>world",
+			want:      "Hello world",
+			forbidden: []string{"<script", "alert(1)"},
+		},
+		{
+			name:      "script in HTML",
+			content:   "<p>Hello</p><script>alert(1)</script>",
+			want:      "<p>Hello</p>",
+			forbidden: []string{"<script", "alert(1)"},
+		},
+		{
+			name:      "onerror handler",
+			content:   `Look <img src="/api/uploads/a.png" alt="a" onerror="alert(1)">`,
+			want:      `Look <img src="/api/uploads/a.png" alt="a">`,
+			forbidden: []string{"onerror"},
+		},
+		{
+			name:      "onload handler in HTML",
+			content:   `<p>Hi</p><body onload="alert(1)"><p>there</p></body>`,
+			forbidden: []string{"onload", "alert(1)"},
+			rendered:  []string{"<p>Hi</p>", "<p>there</p>"},
+		},
+		{
+			name:      "svg onload",
+			content:   "Icon <svg onload=alert(1)></svg> here",
+			forbidden: []string{"<svg", "onload"},
+			rendered:  []string{"Icon", "here"},
+		},
+		{
+			name:      "javascript href in HTML",
+			content:   `<p><a href="javascript:alert(1)">click</a></p>`,
+			forbidden: []string{"javascript:"},
+			rendered:  []string{"click"},
+		},
+		{
+			name:      "javascript href in Markdown inline HTML",
+			content:   `See <a href="javascript:alert(1)">this</a>`,
+			forbidden: []string{"javascript:"},
+			rendered:  []string{"this"},
+		},
+		{
+			// Markdown links are text to the sanitizer; the renderer drops the URL
+			name:        "javascript link in Markdown",
+			content:     "[x](javascript:alert(1))",
+			want:        "[x](javascript:alert(1))",
+			rendered:    []string{"x"},
+			notRendered: []string{"javascript:"},
+		},
+		{
+			name:      "iframe from an unknown host",
+			content:   `Watch <iframe src="https://evil.example.com/embed/1"></iframe> now`,
+			want:      "Watch  now",
+			forbidden: []string{"<iframe", "evil.example.com"},
+		},
+		{
+			name:     "YouTube embed",
+			content:  `<iframe src="https://www.youtube.com/embed/dQw4w9WgXcQ" width="560" height="315" allowfullscreen></iframe>`,
+			want:     `<iframe src="https://www.youtube.com/embed/dQw4w9WgXcQ" width="560" height="315" allowfullscreen=""></iframe>`,
+			rendered: []string{`<iframe src="https://www.youtube.com/embed/dQw4w9WgXcQ"`},
+		},
+		{
+			name:      "code spans",
+			content:   "Fixed the `<select>` dropdown and `<script>` loader",
+			want:      "Fixed the `&lt;select&gt;` dropdown and `&lt;script&gt;` loader",
+			forbidden: []string{"<select", "<script"},
+			rendered:  []string{"<code>&lt;select&gt;</code> dropdown", "<code>&lt;script&gt;</code> loader"},
+		},
+		{
+			name:      "code span with a handler",
+			content:   "Use ``<img onerror=\"x\">`` instead",
+			want:      "Use ``&lt;img onerror=\"x\"&gt;`` instead",
+			forbidden: []string{"<img"},
+			rendered:  []string{"<code>&lt;img onerror=&#34;x&#34;&gt;</code> instead"},
+		},
+		{
+			name:      "fenced code block",
+			content:   "Embed it:\n\n```html\n<script src=\"widget.js\"></script>\n<div id=\"widget\"></div>\n```\n\nThat's all.",
+			want:      "Embed it:\n\n```html\n&lt;script src=\"widget.js\"&gt;&lt;/script&gt;\n&lt;div id=\"widget\"&gt;&lt;/div&gt;\n```\n\nThat's all.",
+			forbidden: []string{"<script", "<div"},
+			rendered:  []string{"&lt;script src=&#34;widget.js&#34;&gt;&lt;/script&gt;", "<p>That&#39;s all.</p>"},
+		},
+		{
+			name:      "indented code block",
+			content:   "Example:\n\n    <script>run()</script>\n\nDone",
+			want:      "Example:\n\n    &lt;script&gt;run()&lt;/script&gt;\n\nDone",
+			forbidden: []string{"<script"},
+			rendered:  []string{"&lt;script&gt;run()&lt;/script&gt;"},
+		},
+		{
+			name:      "script next to a code span",
+			content:   "Run `make` <script>alert(1)</script>then `<b>` and <b>bold</b>",
+			want:      "Run `make` then `&lt;b&gt;` and <b>bold</b>",
+			forbidden: []string{"<script", "alert(1)"},
+		},
+		{
+			name:     "ampersands and entities in code",
+			content:  "Use `a && b` or `&lt;br&gt;` <br>",
+			want:     "Use `a &amp;&amp; b` or `&lt;br&gt;` <br>",
+			rendered: []string{"<code>a &amp;&amp; b</code>", "<code>&lt;br&gt;</code>"},
+		},
+		{
+			name:      "escaped backticks are not a code span",
+			content:   "\\`<script>alert(1)</script>\\`",
+			want:      "\\`\\`",
+			forbidden: []string{"<script", "alert(1)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sanitized := Sanitize(tt.content)
+			if tt.want != "" && sanitized != tt.want {
+				t.Errorf("Sanitize() = %q, want %q", sanitized, tt.want)
+			}
+
+			if again := Sanitize(sanitized); again != sanitized {
+				t.Errorf("Sanitize() twice = %q, want %q", again, sanitized)
+			}
+
+			rendered := ToHTML(sanitized)
+			for _, s := range tt.forbidden {
+				if strings.Contains(sanitized, s) {
+					t.Errorf("Sanitize() = %q, contains %q", sanitized, s)
+				}
+				if strings.Contains(rendered, s) {
+					t.Errorf("ToHTML() = %q, contains %q", rendered, s)
+				}
+			}
+			for _, s := range tt.rendered {
+				if !strings.Contains(rendered, s) {
+					t.Errorf("ToHTML() = %q, want it to contain %q", rendered, s)
+				}
+			}
+			for _, s := range tt.notRendered {
+				if strings.Contains(rendered, s) {
+					t.Errorf("ToHTML() = %q, contains %q", rendered, s)
+				}
+			}
+
+			// Rendering the unsanitized content is safe on its own as well
+			unsanitized := ToHTML(tt.content)
+			for _, s := range tt.forbidden {
+				if strings.Contains(unsanitized, s) {
+					t.Errorf("ToHTML() of the original content = %q, contains %q", unsanitized, s)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"shipshipship/database"
+	"shipshipship/models"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -21,14 +24,18 @@ func init() {
 }
 
 type Claims struct {
-	Username string `json:"username"`
+	UserID   uint            `json:"user_id"`
+	Username string          `json:"username"`
+	Role     models.UserRole `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(username string) (string, error) {
+func GenerateToken(user *models.User) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
-		Username: username,
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -61,6 +68,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Skip authentication in demo mode
 		if IsDemoMode() {
 			c.Set("username", "demo")
+			c.Set("role", models.RoleOwner)
 			c.Next()
 			return
 		}
@@ -86,23 +94,36 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		c.Set("username", claims.Username)
+		// Load the account so role changes and deletions take effect immediately
+		var user models.User
+		if err := database.GetDB().First(&user, claims.UserID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User no longer exists"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("role", user.Role)
 		c.Next()
 	}
 }
 
-func CheckAdminCredentials(username, password string) bool {
-	adminUsername := os.Getenv("ADMIN_USERNAME")
-	adminPassword := os.Getenv("ADMIN_PASSWORD")
+// RequireRole rejects requests from users whose role is below the required role.
+// Must be used after AuthMiddleware.
+func RequireRole(required models.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		userRole, _ := role.(models.UserRole)
 
-	if adminUsername == "" {
-		adminUsername = "admin"
-	}
-	if adminPassword == "" {
-		adminPassword = "admin"
-	}
+		if !models.RoleAtLeast(userRole, required) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
 
-	return username == adminUsername && password == adminPassword
+		c.Next()
+	}
 }
 
 func IsDemoMode() bool {
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The content version changes whenever public content may have changed. It starts at
+// the boot time so ETags handed out before a restart never match afterwards.
+var (
+	contentMutex    sync.RWMutex
+	contentVersion  = uint64(time.Now().UnixNano())
+	contentModified = time.Now()
+)
+
+// BumpContentVersion invalidates the ETags of all cached public responses
+func BumpContentVersion() {
+	contentMutex.Lock()
+	defer contentMutex.Unlock()
+	contentVersion++
+	contentModified = time.Now()
+}
+
+func currentContentVersion() (uint64, time.Time) {
+	contentMutex.RLock()
+	defer contentMutex.RUnlock()
+	return contentVersion, contentModified
+}
+
+// PublicCache adds a strong ETag, Last-Modified and Cache-Control to a public GET
+// endpoint and answers conditional requests with 304 Not Modified while the content
+// version is unchanged. perClient marks responses that contain data of the caller,
+// such as their own reactions; those are only cached privately by the browser and
+// need Visitor to run first.
+//
+// PUBLIC_CACHE_MAX_AGE sets how long browsers may reuse a response without
+// revalidating (default 0), CDN_CACHE_MAX_AGE how long shared caches may
+// (s-maxage, shared responses only).
+func PublicCache(perClient bool) gin.HandlerFunc {
+	cacheControl := publicCacheControl(perClient)
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		version, modified := currentContentVersion()
+		key := fmt.Sprintf("%d|%s", version, c.Request.URL.RequestURI())
+		if perClient {
+			key += "|" + VisitorIdentity(c)
+		}
+		sum := sha256.Sum256([]byte(key))
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+		header := c.Writer.Header()
+		header.Set("ETag", etag)
+		header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		header.Set("Cache-Control", cacheControl)
+
+		if notModified(c.Request, etag, modified) {
+			c.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+
+		c.Writer = &cacheResponseWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// cacheResponseWriter drops the caching headers from error responses
+type cacheResponseWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *cacheResponseWriter) WriteHeader(code int) {
+	if code != http.StatusOK {
+		w.Header().Del("ETag")
+		w.Header().Del("Last-Modified")
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when no ETags were sent
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if since, err := http.ParseTime(ims); err == nil {
+			return !modified.Truncate(time.Second).After(since)
+		}
+	}
+	return false
+}
+
+func publicCacheControl(perClient bool) string {
+	maxAge := cacheSeconds("PUBLIC_CACHE_MAX_AGE")
+	sharedMaxAge := cacheSeconds("CDN_CACHE_MAX_AGE")
+	if perClient {
+		sharedMaxAge = 0
+	}
+
+	directives := []string{"public"}
+	if perClient {
+		directives = []string{"private"}
+	}
+	if maxAge == 0 && sharedMaxAge == 0 {
+		// Caches may store the response but must revalidate it on every use
+		return strings.Join(append(directives, "no-cache"), ", ")
+	}
+	directives = append(directives, fmt.Sprintf("max-age=%d", maxAge))
+	if sharedMaxAge > 0 {
+		directives = append(directives, fmt.Sprintf("s-maxage=%d", sharedMaxAge))
+	}
+	return strings.Join(directives, ", ")
+}
+
+func cacheSeconds(name string) int {
+	seconds, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name)))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return seconds
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -47,6 +48,9 @@ func init() {
 			select {
 			case <-ticker.C:
 				feedbackLimiter.cleanupOldEntries()
+				reactionIdentityLimiter.cleanupOldEntries()
+				reactionIPLimiter.cleanupOldEntries()
+				subscribeLimiter.cleanupOldEntries()
 			}
 		}
 	}()
@@ -116,3 +120,97 @@ func FeedbackRateLimit() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// windowLimiter allows a number of requests per key within a fixed window
+type windowLimiter struct {
+	limit   int
+	window  time.Duration
+	clients map[string]*ClientData
+	mutex   sync.Mutex
+}
+
+func newWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return &windowLimiter{limit: limit, window: window, clients: make(map[string]*ClientData)}
+}
+
+// allow counts a request for key and returns how long to wait when over the limit
+func (wl *windowLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	wl.mutex.Lock()
+	defer wl.mutex.Unlock()
+
+	clientData, exists := wl.clients[key]
+	if !exists || now.After(clientData.resetTime) {
+		clientData = &ClientData{resetTime: now.Add(wl.window)}
+		wl.clients[key] = clientData
+	}
+	if clientData.submissionCount >= wl.limit {
+		return false, clientData.resetTime.Sub(now)
+	}
+	clientData.submissionCount++
+	clientData.lastSubmission = now
+	return true, 0
+}
+
+func (wl *windowLimiter) cleanupOldEntries() {
+	wl.mutex.Lock()
+	defer wl.mutex.Unlock()
+
+	now := time.Now()
+	for key, data := range wl.clients {
+		if now.After(data.resetTime) {
+			delete(wl.clients, key)
+		}
+	}
+}
+
+// Reactions and votes per visitor, and per IP address so clearing cookies to get
+// a new visitor identity doesn't lift the limit
+var (
+	reactionIdentityLimiter = newWindowLimiter(30, time.Minute)
+	reactionIPLimiter       = newWindowLimiter(120, time.Minute)
+)
+
+// ReactionRateLimit limits how often a visitor can react or vote. Use after Visitor.
+func ReactionRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		allowed, wait := reactionIdentityLimiter.allow(VisitorIdentity(c), now)
+		if allowed {
+			allowed, wait = reactionIPLimiter.allow(HashIP(c.ClientIP()), now)
+		}
+		if !allowed {
+			retryAfter := int(wait.Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many reactions. Please wait before trying again.",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Newsletter subscriptions per IP address; each one can send a confirmation email
+var subscribeLimiter = newWindowLimiter(5, 10*time.Minute)
+
+// SubscribeRateLimit limits how often an IP address can subscribe to the newsletter
+func SubscribeRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, wait := subscribeLimiter.allow(c.ClientIP(), time.Now())
+		if !allowed {
+			retryAfter := int(wait.Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many subscription requests. Please wait before trying again.",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
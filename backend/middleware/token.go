@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Purposes of signed link tokens, so a token issued for one action can't be used for another
+const (
+	TokenPurposeNewsletterConfirm     = "newsletter_confirm"
+	TokenPurposeNewsletterUnsubscribe = "newsletter_unsubscribe"
+	TokenPurposeNewsletterPreferences = "newsletter_preferences"
+	TokenPurposeNewsletterTracking    = "newsletter_tracking"
+)
+
+// LinkClaims identify the subject of a signed link sent by email
+type LinkClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateLinkToken signs a token for an email link. A zero ttl creates a token that never expires.
+func GenerateLinkToken(purpose, subject string, ttl time.Duration) (string, error) {
+	claims := &LinkClaims{
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  subject,
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if ttl > 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// ValidateLinkToken checks a link token's signature, expiry and purpose and returns its subject
+func ValidateLinkToken(tokenString, purpose string) (string, error) {
+	claims := &LinkClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return "", err
+	}
+
+	if !token.Valid || claims.Purpose != purpose || claims.Subject == "" {
+		return "", fmt.Errorf("invalid token")
+	}
+
+	return claims.Subject, nil
+}
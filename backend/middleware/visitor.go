@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	visitorCookieName   = "ssv"
+	visitorCookieMaxAge = 365 * 24 * 60 * 60
+	visitorIdentityKey  = "visitor_identity"
+	visitorIssuedAtKey  = "visitor_issued_at"
+
+	// A new cookie only counts for reactions and votes after this long, so clearing
+	// cookies does not give a new vote every time
+	visitorCookieMinAge = 10 * time.Minute
+)
+
+// Visitor identifies anonymous visitors for reactions and votes by a signed cookie,
+// so people sharing an IP address are told apart. The cookie only counts once the
+// browser sends it back; until then, and for clients that drop cookies, the visitor
+// is identified by a hash of their IP address.
+func Visitor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if visitorID, issuedAt, ok := visitorFromCookie(c); ok {
+			c.Set(visitorIdentityKey, "v:"+visitorID)
+			c.Set(visitorIssuedAtKey, issuedAt)
+		} else {
+			c.Set(visitorIdentityKey, "ip:"+HashIP(c.ClientIP()))
+			setVisitorCookie(c)
+		}
+		c.Next()
+	}
+}
+
+// VisitorIdentity returns the identity of the anonymous visitor making the request
+func VisitorIdentity(c *gin.Context) string {
+	if identity := c.GetString(visitorIdentityKey); identity != "" {
+		return identity
+	}
+	if visitorID, _, ok := visitorFromCookie(c); ok {
+		return "v:" + visitorID
+	}
+	return VisitorIPIdentity(c)
+}
+
+// VisitorIPIdentity returns the identity of the visitor's IP address, which reactions
+// and votes are stored under for visitors without an established cookie
+func VisitorIPIdentity(c *gin.Context) string {
+	return "ip:" + HashIP(c.ClientIP())
+}
+
+// VisitorIdentities returns the identities the visitor's reactions and votes may be
+// stored under: the cookie identity and, for cookie visitors, the IP identity used
+// before the cookie was established
+func VisitorIdentities(c *gin.Context) []string {
+	identity := VisitorIdentity(c)
+	if ipIdentity := VisitorIPIdentity(c); ipIdentity != identity {
+		return []string{identity, ipIdentity}
+	}
+	return []string{identity}
+}
+
+// VoterIdentity returns the identity the visitor reacts and votes with. A cookie
+// issued less than visitorCookieMinAge ago is ignored in favor of the IP identity.
+func VoterIdentity(c *gin.Context) string {
+	identity := VisitorIdentity(c)
+	if !strings.HasPrefix(identity, "v:") {
+		return identity
+	}
+	issuedAt, ok := c.Get(visitorIssuedAtKey)
+	if !ok {
+		_, issuedAt, _ = visitorFromCookie(c)
+	}
+	if time.Since(issuedAt.(time.Time)) < visitorCookieMinAge {
+		return VisitorIPIdentity(c)
+	}
+	return identity
+}
+
+// HashIP returns a keyed hash of an IP address; raw addresses are never stored. The
+// key combines the server secret with a random key replaced every
+// IP_HASH_ROTATION_DAYS (default 30), so the same address hashes differently after
+// a rotation.
+func HashIP(ip string) string {
+	mac := hmac.New(sha256.New, currentIPHashKey())
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// The IP hash key is looked up again after this long to pick up rotations
+const ipHashKeyRefresh = time.Minute
+
+var ipHashKey struct {
+	sync.Mutex
+	key     []byte
+	checked time.Time
+}
+
+func currentIPHashKey() []byte {
+	ipHashKey.Lock()
+	defer ipHashKey.Unlock()
+
+	if ipHashKey.key != nil && time.Since(ipHashKey.checked) < ipHashKeyRefresh {
+		return ipHashKey.key
+	}
+	db := database.GetDB()
+	if db == nil {
+		return jwtSecret
+	}
+	stored, err := models.GetOrRotateIPHashKey(db, IPHashRotation())
+	if err != nil {
+		fmt.Printf("Warning: Failed to load IP hash key: %v\n", err)
+		if ipHashKey.key != nil {
+			return ipHashKey.key
+		}
+		return jwtSecret
+	}
+
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("ip-hash:" + stored.Key))
+	ipHashKey.key = mac.Sum(nil)
+	ipHashKey.checked = time.Now()
+	return ipHashKey.key
+}
+
+// IPHashRotation returns how often the IP hash key is replaced
+func IPHashRotation() time.Duration {
+	days, err := strconv.Atoi(strings.TrimSpace(os.Getenv("IP_HASH_ROTATION_DAYS")))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// visitorFromCookie returns the visitor ID and issue time of a valid visitor cookie.
+// Cookies issued before they carried the time count as issued long ago.
+func visitorFromCookie(c *gin.Context) (string, time.Time, bool) {
+	value, err := c.Cookie(visitorCookieName)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	parts := strings.Split(value, ".")
+	var visitorID, issued, signature string
+	switch len(parts) {
+	case 2:
+		visitorID, signature = parts[0], parts[1]
+	case 3:
+		visitorID, issued, signature = parts[0], parts[1], parts[2]
+	default:
+		return "", time.Time{}, false
+	}
+	if visitorID == "" {
+		return "", time.Time{}, false
+	}
+	if !hmac.Equal([]byte(signature), []byte(visitorSignature(visitorID, issued))) {
+		return "", time.Time{}, false
+	}
+
+	var issuedAt time.Time
+	if issued != "" {
+		seconds, err := strconv.ParseInt(issued, 10, 64)
+		if err != nil {
+			return "", time.Time{}, false
+		}
+		issuedAt = time.Unix(seconds, 0)
+	}
+	return visitorID, issuedAt, true
+}
+
+func setVisitorCookie(c *gin.Context) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return
+	}
+	visitorID := base64.RawURLEncoding.EncodeToString(buf)
+	issued := strconv.FormatInt(time.Now().Unix(), 10)
+	value := visitorID + "." + issued + "." + visitorSignature(visitorID, issued)
+
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(visitorCookieName, value, visitorCookieMaxAge, "/", "", secure, true)
+}
+
+// visitorSignature signs a visitor ID and the time it was issued, if any
+func visitorSignature(visitorID, issued string) string {
+	value := "visitor:" + visitorID
+	if issued != "" {
+		value += "." + issued
+	}
+	return base64.RawURLEncoding.EncodeToString(visitorMAC(value)[:16])
+}
+
+// visitorMAC signs a visitor ID with the server secret
+func visitorMAC(value string) []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
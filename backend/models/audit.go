@@ -0,0 +1,186 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audit actions recorded for admin mutations
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionApply  = "apply"
+	AuditActionSend   = "send"
+	AuditActionImport = "import"
+)
+
+// JSONText is a JSON document stored as text that is emitted as raw JSON in API responses
+type JSONText string
+
+// MarshalJSON returns the stored JSON as-is, or null when empty
+func (j JSONText) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
+
+// AuditEntry records a single admin mutation
+type AuditEntry struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Actor      string    `json:"actor" gorm:"not null;index"`       // username from the auth context
+	Action     string    `json:"action" gorm:"not null;index"`      // create, update, delete, apply, send
+	EntityType string    `json:"entity_type" gorm:"not null;index"` // event, tag, status, settings, ...
+	EntityID   string    `json:"entity_id" gorm:"index"`
+	Before     JSONText  `json:"before" gorm:"type:text"`  // JSON snapshot before the change
+	After      JSONText  `json:"after" gorm:"type:text"`   // JSON snapshot after the change
+	Changes    JSONText  `json:"changes" gorm:"type:text"` // JSON object of changed fields: {"field": {"before": x, "after": y}}
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// AuditFilter narrows down audit log queries
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+// FieldChange holds the old and new value of a changed field
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// RecordAudit stores an audit entry for a mutation. before and after are
+// marshalled to JSON; pass nil for the side that does not exist (create/delete).
+// Updates that don't change anything are skipped.
+func RecordAudit(db *gorm.DB, actor, action, entityType string, entityID interface{}, before, after interface{}) error {
+	beforeJSON, err := marshalAuditSnapshot(before)
+	if err != nil {
+		return fmt.Errorf("failed to marshal before snapshot: %w", err)
+	}
+	afterJSON, err := marshalAuditSnapshot(after)
+	if err != nil {
+		return fmt.Errorf("failed to marshal after snapshot: %w", err)
+	}
+
+	// Only diff when both sides exist; creates and deletes keep the full snapshot
+	changesJSON := []byte{}
+	if beforeJSON != "" && afterJSON != "" {
+		changes := DiffJSON(beforeJSON, afterJSON)
+		if action == AuditActionUpdate && len(changes) == 0 {
+			return nil
+		}
+
+		changesJSON, err = json.Marshal(changes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal changes: %w", err)
+		}
+	}
+
+	if actor == "" {
+		actor = "system"
+	}
+
+	entry := AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprintf("%v", entityID),
+		Before:     JSONText(beforeJSON),
+		After:      JSONText(afterJSON),
+		Changes:    JSONText(changesJSON),
+	}
+
+	return db.Create(&entry).Error
+}
+
+// marshalAuditSnapshot marshals a snapshot, returning an empty string for nil
+func marshalAuditSnapshot(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if string(data) == "null" {
+		return "", nil
+	}
+	return string(data), nil
+}
+
+// DiffJSON compares two JSON objects and returns the top-level fields that differ
+func DiffJSON(before, after string) map[string]FieldChange {
+	beforeMap := map[string]interface{}{}
+	afterMap := map[string]interface{}{}
+
+	if before != "" {
+		json.Unmarshal([]byte(before), &beforeMap)
+	}
+	if after != "" {
+		json.Unmarshal([]byte(after), &afterMap)
+	}
+
+	changes := make(map[string]FieldChange)
+	for key, oldValue := range beforeMap {
+		newValue, exists := afterMap[key]
+		if !exists || !reflect.DeepEqual(oldValue, newValue) {
+			changes[key] = FieldChange{Before: oldValue, After: newValue}
+		}
+	}
+	for key, newValue := range afterMap {
+		if _, exists := beforeMap[key]; !exists {
+			changes[key] = FieldChange{Before: nil, After: newValue}
+		}
+	}
+
+	// Timestamps always move on save and only add noise
+	delete(changes, "updated_at")
+
+	return changes
+}
+
+// GetAuditEntries returns paginated audit entries matching the filter, newest first
+func GetAuditEntries(db *gorm.DB, filter AuditFilter, page, limit int) ([]AuditEntry, int64, error) {
+	var entries []AuditEntry
+	var total int64
+
+	query := db.Model(&AuditEntry{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated records
+	offset := (page - 1) * limit
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&entries).Error
+	return entries, total, err
+}
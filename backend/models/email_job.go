@@ -0,0 +1,156 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Email campaign statuses
+const (
+	CampaignStatusQueued    = "queued"
+	CampaignStatusSending   = "sending"
+	CampaignStatusCompleted = "completed"
+)
+
+// Email job statuses (one job per recipient)
+const (
+	EmailJobQueued  = "queued"
+	EmailJobSending = "sending"
+	EmailJobSent    = "sent"
+	EmailJobFailed  = "failed"
+	EmailJobBounced = "bounced"
+	// The address was on the suppression list when the job was due
+	EmailJobSuppressed = "suppressed"
+)
+
+// EmailCampaign groups the email jobs of one newsletter send
+type EmailCampaign struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	EventID     uint       `json:"event_id" gorm:"not null;index"`
+	EventStatus string     `json:"event_status"`
+	Subject     string     `json:"subject"`
+	Content     string     `json:"-" gorm:"type:text"` // HTML with {{unsubscribe_url}} still in place
+	Template    string     `json:"template"`
+	BaseURL     string     `json:"-"` // used to build per-recipient unsubscribe links
+	Automated   bool       `json:"automated" gorm:"default:false"`
+	Tracked     bool       `json:"tracked" gorm:"default:false"` // open and click tracking was added to the content
+	Status      string     `json:"status" gorm:"not null;index"` // queued, sending, completed
+	Total       int        `json:"total"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// EmailJob is a single queued email to one recipient of a campaign
+type EmailJob struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	CampaignID    uint       `json:"campaign_id" gorm:"not null;index"`
+	Email         string     `json:"email" gorm:"not null"`
+	Status        string     `json:"status" gorm:"not null;index"` // queued, sending, sent, failed, bounced, suppressed
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	SentAt        *time.Time `json:"sent_at"`
+	OpenedAt      *time.Time `json:"opened_at"`  // first tracked open
+	ClickedAt     *time.Time `json:"clicked_at"` // first tracked click
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// CampaignProgress summarizes the per-recipient status of a campaign
+type CampaignProgress struct {
+	Total      int64   `json:"total"`
+	Queued     int64   `json:"queued"`
+	Sending    int64   `json:"sending"`
+	Sent       int64   `json:"sent"`
+	Failed     int64   `json:"failed"`
+	Bounced    int64   `json:"bounced"`
+	Suppressed int64   `json:"suppressed"`
+	Percent    float64 `json:"percent"` // share of jobs that reached a final state
+	Finished   bool    `json:"finished"`
+}
+
+// GetCampaignProgress counts the jobs of a campaign by status
+func GetCampaignProgress(db *gorm.DB, campaignID uint) (*CampaignProgress, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&EmailJob{}).
+		Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaignID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	progress := &CampaignProgress{}
+	for _, row := range rows {
+		progress.Total += row.Count
+		switch row.Status {
+		case EmailJobQueued:
+			progress.Queued = row.Count
+		case EmailJobSending:
+			progress.Sending = row.Count
+		case EmailJobSent:
+			progress.Sent = row.Count
+		case EmailJobFailed:
+			progress.Failed = row.Count
+		case EmailJobBounced:
+			progress.Bounced = row.Count
+		case EmailJobSuppressed:
+			progress.Suppressed = row.Count
+		}
+	}
+
+	done := progress.Sent + progress.Failed + progress.Bounced + progress.Suppressed
+	if progress.Total > 0 {
+		progress.Percent = float64(done) * 100 / float64(progress.Total)
+	}
+	progress.Finished = done == progress.Total
+
+	return progress, nil
+}
+
+// GetCampaignsPaginated returns campaigns newest first, optionally for one event
+func GetCampaignsPaginated(db *gorm.DB, eventID uint, page, limit int) ([]EmailCampaign, int64, error) {
+	var campaigns []EmailCampaign
+	var total int64
+
+	query := db.Model(&EmailCampaign{})
+	if eventID != 0 {
+		query = query.Where("event_id = ?", eventID)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated records
+	offset := (page - 1) * limit
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&campaigns).Error
+	return campaigns, total, err
+}
+
+// GetCampaignJobsPaginated returns the jobs of a campaign, optionally filtered by status
+func GetCampaignJobsPaginated(db *gorm.DB, campaignID uint, status string, page, limit int) ([]EmailJob, int64, error) {
+	var jobs []EmailJob
+	var total int64
+
+	query := db.Model(&EmailJob{}).Where("campaign_id = ?", campaignID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated records
+	offset := (page - 1) * limit
+	err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&jobs).Error
+	return jobs, total, err
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Email suppression reasons
+const (
+	SuppressionHardBounce = "hard_bounce"
+	SuppressionComplaint  = "complaint"
+	SuppressionManual     = "manual"
+)
+
+// EmailSuppression is an address no email is sent to anymore, because it bounced
+// permanently, its owner reported a newsletter as spam, or an admin added it
+type EmailSuppression struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Email     string    `json:"email" gorm:"uniqueIndex;not null"` // stored lowercase
+	Reason    string    `json:"reason" gorm:"not null"`            // hard_bounce, complaint or manual
+	Source    string    `json:"source"`                            // smtp, maildir, a webhook provider or admin
+	Detail    string    `json:"detail"`                            // bounce diagnostic or complaint type
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NormalizeSuppressedEmail returns the form addresses are stored and looked up in
+func NormalizeSuppressedEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsEmailSuppressed reports whether sending to an address is suppressed
+func IsEmailSuppressed(db *gorm.DB, email string) (bool, error) {
+	var count int64
+	err := db.Model(&EmailSuppression{}).Where("email = ?", NormalizeSuppressedEmail(email)).Count(&count).Error
+	return count > 0, err
+}
+
+// SuppressEmail adds an address to the suppression list. An address that is already
+// suppressed keeps its entry, except that a complaint replaces the reason of a bounce.
+func SuppressEmail(db *gorm.DB, email, reason, source, detail string) (*EmailSuppression, error) {
+	suppression := EmailSuppression{
+		Email:  NormalizeSuppressedEmail(email),
+		Reason: reason,
+		Source: source,
+		Detail: detail,
+	}
+
+	onConflict := clause.OnConflict{Columns: []clause.Column{{Name: "email"}}, DoNothing: true}
+	if reason == SuppressionComplaint {
+		onConflict = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "email"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "source", "detail", "updated_at"}),
+		}
+	}
+	if err := db.Clauses(onConflict).Create(&suppression).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("email = ?", suppression.Email).First(&suppression).Error; err != nil {
+		return nil, err
+	}
+	return &suppression, nil
+}
+
+// GetSuppressionsPaginated returns suppressed addresses newest first, optionally
+// filtered by reason and an address search
+func GetSuppressionsPaginated(db *gorm.DB, reason, search string, page, limit int) ([]EmailSuppression, int64, error) {
+	var suppressions []EmailSuppression
+	var total int64
+
+	query := db.Model(&EmailSuppression{})
+	if reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	if search != "" {
+		query = query.Where("email LIKE ?", "%"+NormalizeSuppressedEmail(search)+"%")
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated records
+	offset := (page - 1) * limit
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&suppressions).Error
+	return suppressions, total, err
+}
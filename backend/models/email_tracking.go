@@ -0,0 +1,179 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Email tracking event types
+const (
+	EmailTrackingOpen  = "open"
+	EmailTrackingClick = "click"
+)
+
+// EmailTrackingEvent records one open or link click of a newsletter by one recipient.
+// The recipient is the job's email; no IP address or user agent is stored.
+type EmailTrackingEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CampaignID uint      `json:"campaign_id" gorm:"not null;index"`
+	JobID      uint      `json:"job_id" gorm:"not null;index"`
+	Type       string    `json:"type" gorm:"not null"` // open or click
+	URL        string    `json:"url"`                  // clicked link
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RecordEmailOpen stores an open and, on the recipient's first open, increments the
+// campaign's newsletter history open count
+func RecordEmailOpen(db *gorm.DB, job *EmailJob) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&EmailTrackingEvent{CampaignID: job.CampaignID, JobID: job.ID, Type: EmailTrackingOpen}).Error; err != nil {
+			return err
+		}
+		return markFirstOpen(tx, job)
+	})
+}
+
+// RecordEmailClick stores a click and, on the recipient's first click, increments the
+// campaign's click count. A click also counts as an open since images may be blocked.
+func RecordEmailClick(db *gorm.DB, job *EmailJob, url string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&EmailTrackingEvent{CampaignID: job.CampaignID, JobID: job.ID, Type: EmailTrackingClick, URL: url}).Error; err != nil {
+			return err
+		}
+		if err := markFirstOpen(tx, job); err != nil {
+			return err
+		}
+
+		result := tx.Model(&EmailJob{}).Where("id = ? AND clicked_at IS NULL", job.ID).Update("clicked_at", time.Now())
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&NewsletterHistory{}).Where("campaign_id = ?", job.CampaignID).
+			UpdateColumn("click_count", gorm.Expr("click_count + 1")).Error
+	})
+}
+
+func markFirstOpen(tx *gorm.DB, job *EmailJob) error {
+	result := tx.Model(&EmailJob{}).Where("id = ? AND opened_at IS NULL", job.ID).Update("opened_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return tx.Model(&NewsletterHistory{}).Where("campaign_id = ?", job.CampaignID).
+		UpdateColumn("open_count", gorm.Expr("open_count + 1")).Error
+}
+
+// CampaignEngagement summarizes opens and clicks of one tracked campaign
+type CampaignEngagement struct {
+	CampaignID  uint       `json:"campaign_id"`
+	EventID     uint       `json:"event_id"`
+	Subject     string     `json:"subject"`
+	SentAt      *time.Time `json:"sent_at"`
+	Recipients  int        `json:"recipients"`
+	Opens       int        `json:"opens"`  // recipients who opened
+	Clicks      int        `json:"clicks"` // recipients who clicked
+	TotalOpens  int64      `json:"total_opens"`
+	TotalClicks int64      `json:"total_clicks"`
+	OpenRate    float64    `json:"open_rate"`  // percent of recipients
+	ClickRate   float64    `json:"click_rate"` // percent of recipients
+}
+
+// EventEngagement sums the engagement of all tracked campaigns of an event
+type EventEngagement struct {
+	EventID    uint    `json:"event_id"`
+	Campaigns  int     `json:"campaigns"`
+	Recipients int     `json:"recipients"`
+	Opens      int     `json:"opens"`
+	Clicks     int     `json:"clicks"`
+	OpenRate   float64 `json:"open_rate"`
+	ClickRate  float64 `json:"click_rate"`
+}
+
+// GetCampaignEngagement returns the engagement of the most recent tracked campaigns
+func GetCampaignEngagement(db *gorm.DB, limit int) ([]CampaignEngagement, error) {
+	var histories []NewsletterHistory
+	if err := db.Joins("JOIN email_campaigns ON email_campaigns.id = newsletter_histories.campaign_id").
+		Where("email_campaigns.tracked = ?", true).
+		Order("newsletter_histories.created_at DESC").
+		Limit(limit).
+		Find(&histories).Error; err != nil {
+		return nil, err
+	}
+
+	campaignIDs := make([]uint, 0, len(histories))
+	for _, history := range histories {
+		campaignIDs = append(campaignIDs, *history.CampaignID)
+	}
+
+	// Total events including repeated opens and clicks
+	var totals []struct {
+		CampaignID uint
+		Type       string
+		Count      int64
+	}
+	if len(campaignIDs) > 0 {
+		if err := db.Model(&EmailTrackingEvent{}).
+			Select("campaign_id, type, COUNT(*) AS count").
+			Where("campaign_id IN ?", campaignIDs).
+			Group("campaign_id, type").
+			Scan(&totals).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	engagement := make([]CampaignEngagement, len(histories))
+	for i, history := range histories {
+		engagement[i] = CampaignEngagement{
+			CampaignID: *history.CampaignID,
+			EventID:    history.EventID,
+			Subject:    history.Subject,
+			SentAt:     history.SentAt,
+			Recipients: history.RecipientCount,
+			Opens:      history.OpenCount,
+			Clicks:     history.ClickCount,
+			OpenRate:   percentOf(history.OpenCount, history.RecipientCount),
+			ClickRate:  percentOf(history.ClickCount, history.RecipientCount),
+		}
+		for _, total := range totals {
+			if total.CampaignID != *history.CampaignID {
+				continue
+			}
+			if total.Type == EmailTrackingOpen {
+				engagement[i].TotalOpens = total.Count
+			} else if total.Type == EmailTrackingClick {
+				engagement[i].TotalClicks = total.Count
+			}
+		}
+	}
+	return engagement, nil
+}
+
+// GetEventEngagement returns the summed engagement of tracked campaigns per event
+func GetEventEngagement(db *gorm.DB) ([]EventEngagement, error) {
+	var engagement []EventEngagement
+	if err := db.Model(&NewsletterHistory{}).
+		Select("newsletter_histories.event_id, COUNT(*) AS campaigns, "+
+			"SUM(newsletter_histories.recipient_count) AS recipients, "+
+			"SUM(newsletter_histories.open_count) AS opens, SUM(newsletter_histories.click_count) AS clicks").
+		Joins("JOIN email_campaigns ON email_campaigns.id = newsletter_histories.campaign_id").
+		Where("email_campaigns.tracked = ?", true).
+		Group("newsletter_histories.event_id").
+		Order("MAX(newsletter_histories.created_at) DESC").
+		Scan(&engagement).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range engagement {
+		engagement[i].OpenRate = percentOf(engagement[i].Opens, engagement[i].Recipients)
+		engagement[i].ClickRate = percentOf(engagement[i].Clicks, engagement[i].Recipients)
+	}
+	return engagement, nil
+}
+
+// percentOf returns part as a percentage of total, rounded to one decimal
+func percentOf(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(int(float64(part)*1000/float64(total)+0.5)) / 10
+}
@@ -34,21 +34,25 @@ type Tag struct {
 }
 
 type Event struct {
-	ID           uint              `json:"id" gorm:"primaryKey"`
-	Title        string            `json:"title" gorm:"not null"`
-	Slug         string            `json:"slug" gorm:"uniqueIndex"`
-	Tags         []Tag             `json:"tags" gorm:"many2many:event_tags;"`
-	Media        string            `json:"media"` // JSON string of array
-	Status       EventStatus       `json:"status" gorm:"not null"`
-	Date         string            `json:"date"`
-	Votes        int               `json:"votes" gorm:"default:0"`
-	Content      string            `json:"content"` // Markdown content
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt    `json:"-" gorm:"index"`
-	IsPublic     bool              `json:"is_public" gorm:"default:true"`      // Controls if event appears on public page
-	HasPublicUrl bool              `json:"has_public_url" gorm:"default:true"` // Controls if event has individual public URL
-	Publication  *EventPublication `json:"publication,omitempty" gorm:"foreignKey:EventID"`
+	ID              uint              `json:"id" gorm:"primaryKey"`
+	Title           string            `json:"title" gorm:"not null"`
+	Slug            string            `json:"slug" gorm:"uniqueIndex"`
+	Tags            []Tag             `json:"tags" gorm:"many2many:event_tags;"`
+	Media           string            `json:"media"` // JSON string of array
+	Status          EventStatus       `json:"status" gorm:"not null"`
+	Date            string            `json:"date"`
+	Votes           int               `json:"votes" gorm:"default:0"`
+	Content         string            `json:"content"`                         // Markdown content, or HTML from the editor
+	ContentHTML     string            `json:"content_html,omitempty" gorm:"-"` // Content rendered for public responses
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt    `json:"-" gorm:"index"`
+	IsPublic        bool              `json:"is_public" gorm:"default:true"`          // Controls if event appears on public page
+	HasPublicUrl    bool              `json:"has_public_url" gorm:"default:true"`     // Controls if event has individual public URL
+	PublishAt       *time.Time        `json:"publish_at" gorm:"index"`                // When the scheduler makes the event public
+	UnpublishAt     *time.Time        `json:"unpublish_at" gorm:"index"`              // When the scheduler hides the event again
+	NotifyOnPublish bool              `json:"notify_on_publish" gorm:"default:false"` // Send the newsletter when a scheduled publish happens
+	Publication     *EventPublication `json:"publication,omitempty" gorm:"foreignKey:EventID"`
 }
 
 type EventPublication struct {
@@ -68,6 +72,7 @@ type EventPublication struct {
 type EventEmailHistory struct {
 	ID              uint      `json:"id" gorm:"primaryKey"`
 	EventID         uint      `json:"event_id" gorm:"not null;index"`
+	CampaignID      *uint     `json:"campaign_id" gorm:"index"` // queue campaign that sent this email
 	EventStatus     string    `json:"event_status"`
 	EmailSubject    string    `json:"email_subject"`
 	EmailTemplate   string    `json:"email_template"` // "upcoming_feature" or "new_release"
@@ -109,8 +114,11 @@ type VoteRequest struct {
 }
 
 type EventPublishRequest struct {
-	IsPublic     *bool `json:"is_public"`
-	HasPublicUrl *bool `json:"has_public_url"`
+	IsPublic        *bool   `json:"is_public"`
+	HasPublicUrl    *bool   `json:"has_public_url"`
+	PublishAt       *string `json:"publish_at"`   // RFC3339 timestamp, empty string clears the schedule
+	UnpublishAt     *string `json:"unpublish_at"` // RFC3339 timestamp, empty string clears the schedule
+	NotifyOnPublish *bool   `json:"notify_on_publish"`
 }
 
 type EventNewsletterRequest struct {
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sort keys for event lists
+const (
+	EventSortCreatedAt = "created_at"
+	EventSortDate      = "date"
+	EventSortReactions = "reactions"
+)
+
+// ErrInvalidEventCursor is returned for a cursor that is malformed or was issued
+// for a different sort order
+var ErrInvalidEventCursor = errors.New("invalid cursor")
+
+// SQL expression each sort key orders by; ties are broken by ID
+var eventSortExpressions = map[string]string{
+	EventSortCreatedAt: "events.created_at",
+	EventSortDate:      "COALESCE(events.date, '')",
+	EventSortReactions: "(SELECT COUNT(*) FROM event_reactions WHERE event_reactions.event_id = events.id AND event_reactions.deleted_at IS NULL)",
+}
+
+// IsEventSortKey reports whether events can be sorted by key
+func IsEventSortKey(key string) bool {
+	_, ok := eventSortExpressions[key]
+	return ok
+}
+
+// EventListParams filters and orders a page of events
+type EventListParams struct {
+	Statuses   []string // status display names, matched case-insensitively
+	TagIDs     []uint   // events with any of these tags
+	From       *time.Time
+	To         *time.Time // exclusive
+	IsPublic   *bool
+	Sort       string
+	Descending bool
+	Cursor     string // next_cursor of the previous page, empty for the first
+	Limit      int
+}
+
+// EventPage is one page of events and the cursor of the next one
+type EventPage struct {
+	Events     []Event
+	Total      int64 // events matching the filters across all pages
+	NextCursor string
+	HasMore    bool
+}
+
+// eventCursor marks the last event of a page by its sort value and ID
+type eventCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         uint   `json:"id"`
+}
+
+// ListEvents returns one page of events using keyset pagination, so pages stay
+// stable while events are added. Reaction counts can change between requests,
+// so pages sorted by reactions may skip or repeat an event.
+func ListEvents(db *gorm.DB, params EventListParams) (*EventPage, error) {
+	sortExpr, ok := eventSortExpressions[params.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort key %q", params.Sort)
+	}
+
+	query := db.Model(&Event{})
+	if len(params.Statuses) > 0 {
+		statuses := make([]string, len(params.Statuses))
+		for i, status := range params.Statuses {
+			statuses[i] = strings.ToLower(status)
+		}
+		query = query.Where("LOWER(events.status) IN ?", statuses)
+	}
+	if len(params.TagIDs) > 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM event_tags WHERE event_tags.event_id = events.id AND event_tags.tag_id IN ?)", params.TagIDs)
+	}
+	if params.From != nil {
+		query = query.Where("events.created_at >= ?", *params.From)
+	}
+	if params.To != nil {
+		query = query.Where("events.created_at < ?", *params.To)
+	}
+	if params.IsPublic != nil {
+		query = query.Where("events.is_public = ?", *params.IsPublic)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if params.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if params.Cursor != "" {
+		cursor, value, err := decodeEventCursor(params.Cursor, params.Sort, params.Descending)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(fmt.Sprintf("(%s, events.id) %s (?, ?)", sortExpr, comparison), value, cursor.ID)
+	}
+
+	var events []Event
+	err := query.Preload("Tags").
+		Order(fmt.Sprintf("%s %s, events.id %s", sortExpr, direction, direction)).
+		Limit(params.Limit + 1).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	page := &EventPage{Events: events, Total: total}
+	if len(events) > params.Limit {
+		page.Events = events[:params.Limit]
+		page.HasMore = true
+		cursor, err := encodeEventCursor(db, page.Events[params.Limit-1], params.Sort, params.Descending)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = cursor
+	}
+	return page, nil
+}
+
+func encodeEventCursor(db *gorm.DB, last Event, sort string, descending bool) (string, error) {
+	cursor := eventCursor{Sort: sort, Descending: descending, ID: last.ID}
+	switch sort {
+	case EventSortCreatedAt:
+		cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case EventSortDate:
+		cursor.Value = last.Date
+	case EventSortReactions:
+		var count int64
+		if err := db.Model(&EventReaction{}).Where("event_id = ?", last.ID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		cursor.Value = strconv.FormatInt(count, 10)
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeEventCursor returns the cursor and its sort value typed for the SQL comparison
+func decodeEventCursor(encoded, sort string, descending bool) (*eventCursor, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, ErrInvalidEventCursor
+	}
+	var cursor eventCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.Descending != descending {
+		return nil, nil, ErrInvalidEventCursor
+	}
+
+	switch sort {
+	case EventSortCreatedAt:
+		value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, nil, ErrInvalidEventCursor
+		}
+		return &cursor, value, nil
+	case EventSortReactions:
+		value, err := strconv.ParseInt(cursor.Value, 10, 64)
+		if err != nil {
+			return nil, nil, ErrInvalidEventCursor
+		}
+		return &cursor, value, nil
+	default:
+		return &cursor, cursor.Value, nil
+	}
+}
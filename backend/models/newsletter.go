@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"shipshipship/constants"
@@ -8,14 +9,25 @@ import (
 	"gorm.io/gorm"
 )
 
+// Subscriber statuses
+const (
+	SubscriberStatusPending   = "pending"   // waiting for the confirmation link to be clicked
+	SubscriberStatusConfirmed = "confirmed" // receives newsletters
+)
+
 type NewsletterSubscriber struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
-	IsActive     bool           `json:"is_active" gorm:"default:true"`
-	SubscribedAt time.Time      `json:"subscribed_at"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	Email              string         `json:"email" gorm:"uniqueIndex;not null"`
+	IsActive           bool           `json:"is_active" gorm:"default:true"`
+	Status             string         `json:"status" gorm:"not null;default:'confirmed';index"` // existing subscribers count as confirmed
+	SubscribedAt       time.Time      `json:"subscribed_at"`
+	ConfirmedAt        *time.Time     `json:"confirmed_at"`
+	ConfirmationSentAt *time.Time     `json:"-"`                  // last confirmation email, to limit resends
+	TagIDs             string         `json:"-" gorm:"type:text"` // JSON array of preferred tag IDs, empty for all tags
+	Categories         string         `json:"-" gorm:"type:text"` // JSON array of preferred theme category IDs, empty for all
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type SubscribeRequest struct {
@@ -23,13 +35,108 @@ type SubscribeRequest struct {
 }
 
 type UnsubscribeRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	Token string `json:"token" binding:"required"` // signed token from the unsubscribe link
+}
+
+type ConfirmSubscriptionRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UpdateSubscriberPreferencesRequest struct {
+	Token      string   `json:"token" binding:"required"` // signed token from the preferences link
+	TagIDs     []uint   `json:"tag_ids"`
+	Categories []string `json:"categories"`
+}
+
+// IsConfirmed reports whether the subscriber confirmed their address
+func (s *NewsletterSubscriber) IsConfirmed() bool {
+	return s.Status == SubscriberStatusConfirmed
+}
+
+// TagIDList returns the tag IDs the subscriber wants newsletters about
+func (s *NewsletterSubscriber) TagIDList() []uint {
+	ids := []uint{}
+	if s.TagIDs != "" {
+		json.Unmarshal([]byte(s.TagIDs), &ids)
+	}
+	return ids
+}
+
+// CategoryList returns the theme categories the subscriber wants newsletters about
+func (s *NewsletterSubscriber) CategoryList() []string {
+	categories := []string{}
+	if s.Categories != "" {
+		json.Unmarshal([]byte(s.Categories), &categories)
+	}
+	return categories
+}
+
+// SetPreferences stores the preferred tags and categories; empty lists mean everything
+func (s *NewsletterSubscriber) SetPreferences(tagIDs []uint, categories []string) {
+	s.TagIDs, s.Categories = "", ""
+	if len(tagIDs) > 0 {
+		data, _ := json.Marshal(tagIDs)
+		s.TagIDs = string(data)
+	}
+	if len(categories) > 0 {
+		data, _ := json.Marshal(categories)
+		s.Categories = string(data)
+	}
+}
+
+// WantsEvent reports whether an event with the given tags and status categories matches the
+// subscriber's preferences. Each kind of preference narrows the selection when set: the event
+// needs one of the preferred tags and its status one of the preferred categories.
+func (s *NewsletterSubscriber) WantsEvent(eventTagIDs []uint, eventCategories []string) bool {
+	if tagIDs := s.TagIDList(); len(tagIDs) > 0 {
+		matched := false
+		for _, want := range tagIDs {
+			for _, id := range eventTagIDs {
+				if id == want {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if categories := s.CategoryList(); len(categories) > 0 {
+		matched := false
+		for _, want := range categories {
+			for _, category := range eventCategories {
+				if category == want {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MarshalJSON includes the decoded preferences in API responses
+func (s NewsletterSubscriber) MarshalJSON() ([]byte, error) {
+	type subscriberAlias NewsletterSubscriber
+	return json.Marshal(struct {
+		subscriberAlias
+		TagIDs     []uint   `json:"tag_ids"`
+		Categories []string `json:"categories"`
+	}{
+		subscriberAlias: subscriberAlias(s),
+		TagIDs:          s.TagIDList(),
+		Categories:      s.CategoryList(),
+	})
 }
 
-// GetActiveSubscriberCount returns the number of active newsletter subscribers
+// GetActiveSubscriberCount returns the number of confirmed newsletter subscribers
 func GetActiveSubscriberCount(db *gorm.DB) (int64, error) {
 	var count int64
-	err := db.Model(&NewsletterSubscriber{}).Count(&count).Error
+	err := db.Model(&NewsletterSubscriber{}).Where("status = ?", SubscriberStatusConfirmed).Count(&count).Error
 	return count, err
 }
 
@@ -43,7 +150,8 @@ func FindSubscriberByEmail(db *gorm.DB, email string) (*NewsletterSubscriber, er
 	return &subscriber, nil
 }
 
-// Subscribe creates a new newsletter subscription or reactivates a soft-deleted one
+// Subscribe creates a pending newsletter subscription or reactivates a soft-deleted one as pending.
+// The subscription only becomes active once confirmed with ConfirmSubscription.
 func Subscribe(db *gorm.DB, email string) (*NewsletterSubscriber, error) {
 	var subscriber NewsletterSubscriber
 
@@ -55,6 +163,7 @@ func Subscribe(db *gorm.DB, email string) (*NewsletterSubscriber, error) {
 			subscriber = NewsletterSubscriber{
 				Email:        email,
 				IsActive:     true,
+				Status:       SubscriberStatusPending,
 				SubscribedAt: time.Now(),
 			}
 			err = db.Create(&subscriber).Error
@@ -70,7 +179,11 @@ func Subscribe(db *gorm.DB, email string) (*NewsletterSubscriber, error) {
 	if subscriber.DeletedAt.Valid {
 		subscriber.DeletedAt = gorm.DeletedAt{}
 		subscriber.IsActive = true
+		subscriber.Status = SubscriberStatusPending
 		subscriber.SubscribedAt = time.Now()
+		subscriber.ConfirmedAt = nil
+		subscriber.ConfirmationSentAt = nil
+		subscriber.SetPreferences(nil, nil)
 		err = db.Unscoped().Save(&subscriber).Error
 		if err != nil {
 			return nil, err
@@ -82,6 +195,43 @@ func Subscribe(db *gorm.DB, email string) (*NewsletterSubscriber, error) {
 	return &subscriber, nil
 }
 
+// ConfirmSubscription marks a pending subscriber as confirmed. Confirming twice is a no-op.
+func ConfirmSubscription(db *gorm.DB, email string) (*NewsletterSubscriber, bool, error) {
+	subscriber, err := FindSubscriberByEmail(db, email)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if subscriber.IsConfirmed() {
+		return subscriber, false, nil
+	}
+
+	now := time.Now()
+	subscriber.Status = SubscriberStatusConfirmed
+	subscriber.ConfirmedAt = &now
+	if err := db.Save(subscriber).Error; err != nil {
+		return nil, false, err
+	}
+	return subscriber, true, nil
+}
+
+// ClaimConfirmationEmail records that a confirmation email is being sent to a pending
+// subscriber. It returns false when one already went out within the cooldown.
+func ClaimConfirmationEmail(db *gorm.DB, subscriber *NewsletterSubscriber, cooldown time.Duration) (bool, error) {
+	now := time.Now()
+	result := db.Model(&NewsletterSubscriber{}).
+		Where("id = ? AND (confirmation_sent_at IS NULL OR confirmation_sent_at < ?)", subscriber.ID, now.Add(-cooldown)).
+		UpdateColumn("confirmation_sent_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	subscriber.ConfirmationSentAt = &now
+	return true, nil
+}
+
 // Unsubscribe removes a newsletter subscription using soft delete
 func Unsubscribe(db *gorm.DB, email string) error {
 	return db.Where("email = ?", email).Delete(&NewsletterSubscriber{}).Error
@@ -89,12 +239,14 @@ func Unsubscribe(db *gorm.DB, email string) error {
 
 type NewsletterHistory struct {
 	ID             uint           `json:"id" gorm:"primaryKey"`
+	CampaignID     *uint          `json:"campaign_id" gorm:"uniqueIndex"` // queue campaign this entry summarizes
+	EventID        uint           `json:"event_id" gorm:"index"`
 	Subject        string         `json:"subject" gorm:"not null"`
 	Content        string         `json:"content" gorm:"type:text;not null"`
 	Status         string         `json:"status" gorm:"not null;default:'draft'"` // draft, sending, sent, failed
 	RecipientCount int            `json:"recipient_count" gorm:"default:0"`
-	OpenCount      int            `json:"open_count" gorm:"default:0"`
-	ClickCount     int            `json:"click_count" gorm:"default:0"`
+	OpenCount      int            `json:"open_count" gorm:"default:0"`  // recipients who opened, tracked campaigns only
+	ClickCount     int            `json:"click_count" gorm:"default:0"` // recipients who clicked a link
 	SentAt         *time.Time     `json:"sent_at"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
@@ -193,13 +345,43 @@ func GetAllEmailTemplates(db *gorm.DB) (map[string]EmailTemplate, error) {
 	return templateMap, nil
 }
 
-// GetActiveNewsletterSubscribers returns all active newsletter subscribers
+// GetActiveNewsletterSubscribers returns all confirmed newsletter subscribers whose
+// address is not on the suppression list
 func GetActiveNewsletterSubscribers(db *gorm.DB) ([]NewsletterSubscriber, error) {
 	var subscribers []NewsletterSubscriber
-	err := db.Find(&subscribers).Error
+	err := db.Where("status = ?", SubscriberStatusConfirmed).
+		Where("LOWER(email) NOT IN (?)", db.Model(&EmailSuppression{}).Select("email")).
+		Find(&subscribers).Error
 	return subscribers, err
 }
 
+// GetNewsletterSubscribersForEvent returns the confirmed subscribers whose topic preferences
+// match the event's tags and the current theme's categories for its status
+func GetNewsletterSubscribersForEvent(db *gorm.DB, event *Event) ([]NewsletterSubscriber, error) {
+	subscribers, err := GetActiveNewsletterSubscribers(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var tagIDs []uint
+	if err := db.Table("event_tags").Where("event_id = ?", event.ID).Pluck("tag_id", &tagIDs).Error; err != nil {
+		return nil, err
+	}
+
+	categories, err := GetStatusCategories(db, string(event.Status))
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []NewsletterSubscriber{}
+	for _, subscriber := range subscribers {
+		if subscriber.WantsEvent(tagIDs, categories) {
+			matching = append(matching, subscriber)
+		}
+	}
+	return matching, nil
+}
+
 // InitializeDefaultEmailTemplates ensures default email templates exist in the database
 func InitializeDefaultEmailTemplates(db *gorm.DB) error {
 	templates := constants.GetDefaultTemplates()
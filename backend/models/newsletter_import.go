@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Errors listed in an import report are capped so huge files stay readable
+const maxImportReportErrors = 100
+
+// errImportDryRun rolls back the transaction of a dry run
+var errImportDryRun = errors.New("dry run")
+
+// Date formats accepted for subscribed_at
+var importDateFormats = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// SubscriberImportRow is one subscriber read from an import file
+type SubscriberImportRow struct {
+	Line         int    `json:"line"` // line or array position in the file, for the report
+	Email        string `json:"email"`
+	Status       string `json:"status"`        // confirmed (default) or pending
+	SubscribedAt string `json:"subscribed_at"` // RFC 3339 or YYYY-MM-DD, defaults to now
+}
+
+// SubscriberImportError describes a row that was not imported
+type SubscriberImportError struct {
+	Line  int    `json:"line"`
+	Email string `json:"email"`
+	Error string `json:"error"`
+}
+
+// SubscriberImportReport summarizes an import, or what an import would do in a dry run
+type SubscriberImportReport struct {
+	DryRun       bool                    `json:"dry_run"`
+	Total        int                     `json:"total"`
+	Created      int                     `json:"created"`
+	Existing     int                     `json:"existing"`     // already subscribed, left unchanged
+	Unsubscribed int                     `json:"unsubscribed"` // unsubscribed or deleted earlier, skipped
+	Duplicates   int                     `json:"duplicates"`   // repeated in the file
+	Suppressed   int                     `json:"suppressed"`   // on the suppression list, skipped
+	Invalid      int                     `json:"invalid"`
+	Errors       []SubscriberImportError `json:"errors"`
+}
+
+func (r *SubscriberImportReport) addError(row SubscriberImportRow, format string, args ...interface{}) {
+	r.Invalid++
+	if len(r.Errors) < maxImportReportErrors {
+		r.Errors = append(r.Errors, SubscriberImportError{Line: row.Line, Email: row.Email, Error: fmt.Sprintf(format, args...)})
+	}
+}
+
+// ImportSubscribers adds subscribers in one transaction. Addresses are compared case-insensitively;
+// existing subscribers are left alone, and addresses that unsubscribed or are suppressed are
+// skipped. A dry run reports the same counts without saving anything.
+func ImportSubscribers(db *gorm.DB, rows []SubscriberImportRow, dryRun bool) (*SubscriberImportReport, error) {
+	report := &SubscriberImportReport{DryRun: dryRun, Total: len(rows), Errors: []SubscriberImportError{}}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing []NewsletterSubscriber
+		if err := tx.Unscoped().Find(&existing).Error; err != nil {
+			return err
+		}
+		byEmail := make(map[string]*NewsletterSubscriber, len(existing))
+		for i := range existing {
+			byEmail[strings.ToLower(existing[i].Email)] = &existing[i]
+		}
+
+		var suppressedEmails []string
+		if err := tx.Model(&EmailSuppression{}).Pluck("email", &suppressedEmails).Error; err != nil {
+			return err
+		}
+		suppressed := make(map[string]bool, len(suppressedEmails))
+		for _, email := range suppressedEmails {
+			suppressed[email] = true
+		}
+
+		seen := make(map[string]bool, len(rows))
+		for _, row := range rows {
+			row.Email = strings.TrimSpace(row.Email)
+			subscriber, err := row.toSubscriber()
+			if err != nil {
+				report.addError(row, "%v", err)
+				continue
+			}
+
+			key := strings.ToLower(subscriber.Email)
+			switch {
+			case seen[key]:
+				report.Duplicates++
+				continue
+			case suppressed[key]:
+				report.Suppressed++
+				seen[key] = true
+				continue
+			}
+			seen[key] = true
+
+			if current, ok := byEmail[key]; ok {
+				// Only the person can subscribe again after unsubscribing
+				if current.DeletedAt.Valid {
+					report.Unsubscribed++
+				} else {
+					report.Existing++
+				}
+				continue
+			}
+
+			if err := tx.Create(&subscriber).Error; err != nil {
+				return err
+			}
+			report.Created++
+		}
+
+		if dryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return nil, err
+	}
+	return report, nil
+}
+
+// toSubscriber validates a row and builds the subscriber it describes
+func (row SubscriberImportRow) toSubscriber() (NewsletterSubscriber, error) {
+	if row.Email == "" {
+		return NewsletterSubscriber{}, fmt.Errorf("email is required")
+	}
+	address, err := mail.ParseAddress(row.Email)
+	if err != nil || address.Address != row.Email {
+		return NewsletterSubscriber{}, fmt.Errorf("invalid email address")
+	}
+
+	subscriber := NewsletterSubscriber{
+		Email:        row.Email,
+		IsActive:     true,
+		Status:       SubscriberStatusConfirmed,
+		SubscribedAt: time.Now(),
+	}
+
+	switch status := strings.ToLower(strings.TrimSpace(row.Status)); status {
+	case "", SubscriberStatusConfirmed:
+	case SubscriberStatusPending:
+		subscriber.Status = SubscriberStatusPending
+	default:
+		return NewsletterSubscriber{}, fmt.Errorf("unknown status %q, expected confirmed or pending", row.Status)
+	}
+
+	if value := strings.TrimSpace(row.SubscribedAt); value != "" {
+		parsed, ok := parseImportDate(value)
+		if !ok {
+			return NewsletterSubscriber{}, fmt.Errorf("invalid subscribed_at %q", value)
+		}
+		subscriber.SubscribedAt = parsed
+	}
+
+	// Imported subscribers already opted in with the previous tool
+	if subscriber.Status == SubscriberStatusConfirmed {
+		confirmedAt := subscriber.SubscribedAt
+		subscriber.ConfirmedAt = &confirmedAt
+	}
+	return subscriber, nil
+}
+
+func parseImportDate(value string) (time.Time, bool) {
+	for _, format := range importDateFormats {
+		if parsed, err := time.Parse(format, value); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IPHashKey is the random part of the key IP addresses are hashed with. It is
+// replaced periodically and the old one deleted, so hashes from earlier periods
+// can no longer be linked to an address.
+type IPHashKey struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	Key       string    `json:"-" gorm:"not null"` // hex, combined with the server secret
+	CreatedAt time.Time `json:"-"`
+}
+
+// GetOrRotateIPHashKey returns the current IP hash key, creating a new one and
+// deleting the others when it is older than rotation
+func GetOrRotateIPHashKey(db *gorm.DB, rotation time.Duration) (*IPHashKey, error) {
+	var current IPHashKey
+	err := db.Order("created_at DESC, id DESC").First(&current).Error
+	if err == nil && time.Since(current.CreatedAt) < rotation {
+		return &current, nil
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	key := IPHashKey{Key: hex.EncodeToString(buf)}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&key).Error; err != nil {
+			return err
+		}
+		return tx.Where("id <> ?", key.ID).Delete(&IPHashKey{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Rotated IP hash key")
+	return &key, nil
+}
+
+// HashStoredIPAddresses replaces raw IP addresses stored on reactions and votes by
+// their hash. Hashes are hex, so addresses are recognized by their dots or colons.
+func HashStoredIPAddresses(db *gorm.DB, hashIP func(string) string) error {
+	for _, table := range []string{"event_reactions", "votes"} {
+		var ips []string
+		err := db.Table(table).Where("ip_address LIKE '%.%' OR ip_address LIKE '%:%'").
+			Distinct().Pluck("ip_address", &ips).Error
+		if err != nil {
+			return fmt.Errorf("failed to list IP addresses in %s: %w", table, err)
+		}
+		for _, ip := range ips {
+			if err := db.Table(table).Where("ip_address = ?", ip).Update("ip_address", hashIP(ip)).Error; err != nil {
+				return fmt.Errorf("failed to hash IP addresses in %s: %w", table, err)
+			}
+		}
+		if len(ips) > 0 {
+			fmt.Printf("Hashed %d IP addresses in %s\n", len(ips), table)
+		}
+	}
+	return nil
+}
+
+// PurgeVisitorIdentities removes what identifies a visitor from reactions and votes
+// given before a time. Their counts stay; removed ones are deleted for good. The
+// visitor is no longer recognized, so they can react to those events again.
+func PurgeVisitorIdentities(db *gorm.DB, before time.Time) (int64, error) {
+	var purged int64
+	for _, model := range []interface{}{&EventReaction{}, &Vote{}} {
+		result := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(model)
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged += result.RowsAffected
+
+		// Identities stay unique per event and reaction, so they become the row's own ID
+		result = db.Model(model).
+			Where("created_at < ? AND identity NOT LIKE 'anon:%'", before).
+			UpdateColumns(map[string]interface{}{
+				"ip_address": "",
+				"identity":   gorm.Expr("'anon:' || id"),
+			})
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged += result.RowsAffected
+	}
+	return purged, nil
+}
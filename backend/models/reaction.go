@@ -1,14 +1,19 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// ReactionType represents the type of reaction
+// ReactionType is the key of a reaction definition
 type ReactionType string
 
+// Keys of the reactions seeded on first start
 const (
 	ReactionThumbsUp   ReactionType = "thumbs_up"   // 👍 Like/Support
 	ReactionHeart      ReactionType = "heart"       // ❤️ Love it
@@ -25,8 +30,9 @@ type EventReaction struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	EventID      uint           `json:"event_id" gorm:"not null;index"`
 	ReactionType ReactionType   `json:"reaction_type" gorm:"not null;index"`
-	IPAddress    string         `json:"ip_address" gorm:"index"`
-	UserID       *uint          `json:"user_id" gorm:"index"` // nullable for anonymous reactions
+	IPAddress    string         `json:"-" gorm:"index"` // keyed hash of the IP address, never the address itself
+	Identity     string         `json:"-" gorm:"index"` // visitor cookie ("v:") or hashed IP ("ip:"), unique per event and reaction
+	UserID       *uint          `json:"-" gorm:"index"` // nullable for anonymous reactions
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
@@ -54,74 +60,283 @@ type ReactionSummary struct {
 	UserReactions []ReactionType  `json:"user_reactions"` // reactions by current user/IP
 }
 
-// GetReactionEmoji returns the emoji for a reaction type
-func GetReactionEmoji(reactionType ReactionType) string {
-	switch reactionType {
-	case ReactionThumbsUp:
-		return "👍"
-	case ReactionHeart:
-		return "❤️"
-	case ReactionFire:
-		return "🔥"
-	case ReactionParty:
-		return "🎉"
-	case ReactionEyes:
-		return "👀"
-	case ReactionLightBulb:
-		return "💡"
-	case ReactionThinking:
-		return "🤔"
-	case ReactionThumbsDown:
-		return "👎"
-	default:
+// ReactionDefinition is a reaction visitors can give. Restrictions are empty for
+// reactions shown everywhere.
+type ReactionDefinition struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Key         ReactionType `json:"key" gorm:"not null;uniqueIndex"` // stored on event reactions
+	Emoji       string       `json:"emoji"`
+	IconURL     string       `json:"icon_url"` // uploaded icon, shown instead of the emoji
+	Label       string       `json:"label" gorm:"not null"`
+	Order       int          `json:"order" gorm:"not null;default:0"`
+	Enabled     bool         `json:"enabled"`
+	ThemeIDs    string       `json:"-" gorm:"type:text"` // JSON array of theme IDs the reaction is shown with, empty for all
+	CategoryIDs string       `json:"-" gorm:"type:text"` // JSON array of theme category IDs the reaction is shown on, empty for all
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type CreateReactionDefinitionRequest struct {
+	Key         ReactionType `json:"key" binding:"required"`
+	Emoji       string       `json:"emoji"`
+	IconURL     string       `json:"icon_url"`
+	Label       string       `json:"label" binding:"required"`
+	Order       *int         `json:"order"`
+	Enabled     *bool        `json:"enabled"`
+	ThemeIDs    []string     `json:"theme_ids"`
+	CategoryIDs []string     `json:"category_ids"`
+}
+
+type UpdateReactionDefinitionRequest struct {
+	Key         *ReactionType `json:"key"`
+	Emoji       *string       `json:"emoji"`
+	IconURL     *string       `json:"icon_url"`
+	Label       *string       `json:"label"`
+	Order       *int          `json:"order"`
+	Enabled     *bool         `json:"enabled"`
+	ThemeIDs    *[]string     `json:"theme_ids"`
+	CategoryIDs *[]string     `json:"category_ids"`
+}
+
+var reactionKeyRegex = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// IsValidReactionKey checks that a key is lowercase letters, digits and underscores
+func IsValidReactionKey(key ReactionType) bool {
+	return reactionKeyRegex.MatchString(string(key))
+}
+
+// ThemeIDList returns the themes the reaction is restricted to
+func (d *ReactionDefinition) ThemeIDList() []string {
+	return decodeStringList(d.ThemeIDs)
+}
+
+// CategoryIDList returns the theme categories the reaction is restricted to
+func (d *ReactionDefinition) CategoryIDList() []string {
+	return decodeStringList(d.CategoryIDs)
+}
+
+// SetRestrictions stores the themes and categories the reaction is restricted to
+func (d *ReactionDefinition) SetRestrictions(themeIDs, categoryIDs []string) {
+	d.ThemeIDs = encodeStringList(themeIDs)
+	d.CategoryIDs = encodeStringList(categoryIDs)
+}
+
+// AppliesTo reports whether the reaction is shown with a theme on an event in one of
+// the given categories. An empty category list matches any category restriction, so
+// reactions can be listed without an event.
+func (d *ReactionDefinition) AppliesTo(themeID string, categories []string) bool {
+	if themes := d.ThemeIDList(); len(themes) > 0 && !containsString(themes, themeID) {
+		return false
+	}
+	allowed := d.CategoryIDList()
+	if len(allowed) == 0 || len(categories) == 0 {
+		return true
+	}
+	for _, category := range categories {
+		if containsString(allowed, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON includes the decoded restriction lists in API responses
+func (d ReactionDefinition) MarshalJSON() ([]byte, error) {
+	type reactionDefinitionAlias ReactionDefinition
+	return json.Marshal(struct {
+		reactionDefinitionAlias
+		ThemeIDs    []string `json:"theme_ids"`
+		CategoryIDs []string `json:"category_ids"`
+	}{
+		reactionDefinitionAlias: reactionDefinitionAlias(d),
+		ThemeIDs:                d.ThemeIDList(),
+		CategoryIDs:             d.CategoryIDList(),
+	})
+}
+
+// GetReactionDefinitions returns the reactions in display order, optionally only the enabled ones
+func GetReactionDefinitions(db *gorm.DB, enabledOnly bool) ([]ReactionDefinition, error) {
+	var definitions []ReactionDefinition
+	query := db.Order("`order` ASC, id ASC")
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	err := query.Find(&definitions).Error
+	return definitions, err
+}
+
+// GetReactionDefinition returns the definition of a reaction key
+func GetReactionDefinition(db *gorm.DB, key ReactionType) (*ReactionDefinition, error) {
+	var definition ReactionDefinition
+	if err := db.Where("`key` = ?", key).First(&definition).Error; err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// Reactions available before they were configurable
+var defaultReactions = []ReactionDefinition{
+	{Key: ReactionThumbsUp, Emoji: "👍", Label: "Like"},
+	{Key: ReactionHeart, Emoji: "❤️", Label: "Love"},
+	{Key: ReactionFire, Emoji: "🔥", Label: "Hot"},
+	{Key: ReactionParty, Emoji: "🎉", Label: "Excited"},
+	{Key: ReactionEyes, Emoji: "👀", Label: "Watching"},
+	{Key: ReactionLightBulb, Emoji: "💡", Label: "Great Idea"},
+	{Key: ReactionThinking, Emoji: "🤔", Label: "Thinking"},
+	{Key: ReactionThumbsDown, Emoji: "👎", Label: "Disagree"},
+}
+
+// SeedReactionDefinitions creates the default reactions on first start and a
+// definition for every reaction key already given to events
+func SeedReactionDefinitions(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&ReactionDefinition{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check existing reactions: %w", err)
+	}
+	if count == 0 {
+		for i, reaction := range defaultReactions {
+			reaction.Order = i
+			reaction.Enabled = true
+			if err := db.Create(&reaction).Error; err != nil {
+				return fmt.Errorf("failed to create reaction %s: %w", reaction.Key, err)
+			}
+		}
+	}
+
+	var usedKeys []string
+	if err := db.Model(&EventReaction{}).Distinct().Pluck("reaction_type", &usedKeys).Error; err != nil {
+		return fmt.Errorf("failed to list used reactions: %w", err)
+	}
+	for _, key := range usedKeys {
+		var existing int64
+		db.Model(&ReactionDefinition{}).Where("`key` = ?", key).Count(&existing)
+		if existing > 0 || key == "" {
+			continue
+		}
+
+		var maxOrder int
+		db.Model(&ReactionDefinition{}).Select("COALESCE(MAX(`order`),0)").Scan(&maxOrder)
+		// Unknown keys are kept so their counts survive, but hidden until configured
+		reaction := ReactionDefinition{Key: ReactionType(key), Label: key, Order: maxOrder + 1}
+		if err := db.Create(&reaction).Error; err != nil {
+			return fmt.Errorf("failed to create reaction %s: %w", key, err)
+		}
+		fmt.Printf("Created reaction definition for existing reactions: %s\n", key)
+	}
+	return nil
+}
+
+func decodeStringList(data string) []string {
+	list := []string{}
+	if data != "" {
+		json.Unmarshal([]byte(data), &list)
+	}
+	return list
+}
+
+func encodeStringList(list []string) string {
+	if len(list) == 0 {
 		return ""
 	}
+	data, _ := json.Marshal(list)
+	return string(data)
 }
 
-// GetReactionLabel returns a human-readable label for a reaction type
-func GetReactionLabel(reactionType ReactionType) string {
-	switch reactionType {
-	case ReactionThumbsUp:
-		return "Like"
-	case ReactionHeart:
-		return "Love"
-	case ReactionFire:
-		return "Hot"
-	case ReactionParty:
-		return "Excited"
-	case ReactionEyes:
-		return "Watching"
-	case ReactionLightBulb:
-		return "Great Idea"
-	case ReactionThinking:
-		return "Thinking"
-	case ReactionThumbsDown:
-		return "Disagree"
-	default:
-		return string(reactionType)
-	}
-}
-
-// ValidReactionTypes returns all valid reaction types
-func ValidReactionTypes() []ReactionType {
-	return []ReactionType{
-		ReactionThumbsUp,
-		ReactionHeart,
-		ReactionFire,
-		ReactionParty,
-		ReactionEyes,
-		ReactionLightBulb,
-		ReactionThinking,
-		ReactionThumbsDown,
-	}
-}
-
-// IsValidReactionType checks if a reaction type is valid
-func IsValidReactionType(rt ReactionType) bool {
-	for _, validType := range ValidReactionTypes() {
-		if rt == validType {
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
 			return true
 		}
 	}
 	return false
 }
+
+// MigrateVisitorIdentities gives reactions and votes stored before visitor identities
+// the identity of their IP address, removes duplicates and adds the unique indexes
+// that keep a visitor from reacting or voting twice
+func MigrateVisitorIdentities(db *gorm.DB, hashIP func(string) string) error {
+	for _, table := range []string{"event_reactions", "votes"} {
+		var ips []string
+		if err := db.Table(table).Where("identity = '' OR identity IS NULL").Distinct().Pluck("ip_address", &ips).Error; err != nil {
+			return fmt.Errorf("failed to list %s without identity: %w", table, err)
+		}
+		for _, ip := range ips {
+			err := db.Table(table).Where("(identity = '' OR identity IS NULL) AND ip_address = ?", ip).
+				Update("identity", "ip:"+hashIP(ip)).Error
+			if err != nil {
+				return fmt.Errorf("failed to set identity on %s: %w", table, err)
+			}
+		}
+	}
+
+	// Concurrent requests could store the same reaction or vote twice
+	result := db.Exec(`DELETE FROM event_reactions WHERE deleted_at IS NULL AND id NOT IN (
+		SELECT MIN(id) FROM event_reactions WHERE deleted_at IS NULL GROUP BY event_id, identity, reaction_type)`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove duplicate reactions: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		fmt.Printf("Removed %d duplicate reactions\n", result.RowsAffected)
+	}
+
+	result = db.Exec(`DELETE FROM votes WHERE deleted_at IS NULL AND id NOT IN (
+		SELECT MIN(id) FROM votes WHERE deleted_at IS NULL GROUP BY event_id, identity)`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove duplicate votes: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		fmt.Printf("Removed %d duplicate votes\n", result.RowsAffected)
+		if err := db.Exec(`UPDATE events SET votes = (
+			SELECT COUNT(*) FROM votes WHERE votes.event_id = events.id AND votes.deleted_at IS NULL)`).Error; err != nil {
+			return fmt.Errorf("failed to recount votes: %w", err)
+		}
+	}
+
+	// Removed reactions and votes are soft-deleted, so only live rows must be unique
+	if err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_event_reactions_unique_identity
+		ON event_reactions (event_id, identity, reaction_type) WHERE deleted_at IS NULL`).Error; err != nil {
+		return fmt.Errorf("failed to create reaction identity index: %w", err)
+	}
+	if err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_votes_unique_identity
+		ON votes (event_id, identity) WHERE deleted_at IS NULL`).Error; err != nil {
+		return fmt.Errorf("failed to create vote identity index: %w", err)
+	}
+	return nil
+}
+
+// ClaimVisitorIdentity moves the reactions and vote on an event stored under a visitor's
+// IP identity to their cookie identity. Rows the cookie identity already has a match
+// for stay where they are.
+func ClaimVisitorIdentity(db *gorm.DB, eventID uint, ipIdentity, identity string) error {
+	err := db.Exec(`UPDATE event_reactions SET identity = ?
+		WHERE event_id = ? AND identity = ? AND deleted_at IS NULL AND reaction_type NOT IN (
+			SELECT reaction_type FROM event_reactions WHERE event_id = ? AND identity = ? AND deleted_at IS NULL)`,
+		identity, eventID, ipIdentity, eventID, identity).Error
+	if err != nil {
+		return fmt.Errorf("failed to claim reactions: %w", err)
+	}
+	err = db.Exec(`UPDATE votes SET identity = ?
+		WHERE event_id = ? AND identity = ? AND deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM votes WHERE event_id = ? AND identity = ? AND deleted_at IS NULL)`,
+		identity, eventID, ipIdentity, eventID, identity).Error
+	if err != nil {
+		return fmt.Errorf("failed to claim vote: %w", err)
+	}
+	return nil
+}
+
+// CountVisitorIdentities returns how many cookie identities other than identity have
+// reacted or voted on an event from a hashed IP address
+func CountVisitorIdentities(db *gorm.DB, eventID uint, ipHash, identity string) (int64, error) {
+	var count int64
+	err := db.Raw(`SELECT COUNT(*) FROM (
+		SELECT identity FROM event_reactions WHERE event_id = ? AND ip_address = ? AND identity LIKE 'v:%' AND identity <> ? AND deleted_at IS NULL
+		UNION SELECT identity FROM votes WHERE event_id = ? AND ip_address = ? AND identity LIKE 'v:%' AND identity <> ? AND deleted_at IS NULL)`,
+		eventID, ipHash, identity, eventID, ipHash, identity).Scan(&count).Error
+	return count, err
+}
+
+// IsDuplicateError reports whether an insert failed on a unique index
+func IsDuplicateError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EventRevision is a snapshot of an event taken every time it is saved
+type EventRevision struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	EventID   uint        `json:"event_id" gorm:"not null;index;uniqueIndex:idx_event_revision"`
+	Revision  int         `json:"revision" gorm:"not null;uniqueIndex:idx_event_revision"` // 1-based, per event
+	Title     string      `json:"title"`
+	Slug      string      `json:"slug"`
+	Status    EventStatus `json:"status"`
+	Date      string      `json:"date"`
+	Media     string      `json:"media"` // JSON string of array, as stored on the event
+	Content   string      `json:"content" gorm:"type:text"`
+	Tags      string      `json:"-" gorm:"type:text"` // JSON array of RevisionTag
+	Actor     string      `json:"actor"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// RevisionTag records a tag association at the time of the snapshot
+type RevisionTag struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// TagList returns the tags stored in the revision
+func (r *EventRevision) TagList() []RevisionTag {
+	tags := []RevisionTag{}
+	if r.Tags != "" {
+		json.Unmarshal([]byte(r.Tags), &tags)
+	}
+	return tags
+}
+
+// TagIDs returns the IDs of the tags stored in the revision
+func (r *EventRevision) TagIDs() []uint {
+	tags := r.TagList()
+	ids := make([]uint, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+	return ids
+}
+
+// MarshalJSON includes the decoded tag list in API responses
+func (r EventRevision) MarshalJSON() ([]byte, error) {
+	type revisionAlias EventRevision
+	return json.Marshal(struct {
+		revisionAlias
+		Tags []RevisionTag `json:"tags"`
+	}{
+		revisionAlias: revisionAlias(r),
+		Tags:          r.TagList(),
+	})
+}
+
+// CreateEventRevision snapshots the current state of an event.
+// The event must have its Tags loaded.
+func CreateEventRevision(db *gorm.DB, event *Event, actor string) (*EventRevision, error) {
+	tags := make([]RevisionTag, len(event.Tags))
+	for i, tag := range event.Tags {
+		tags[i] = RevisionTag{ID: tag.ID, Name: tag.Name}
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	// A concurrent save can take the same revision number; try again with the next one
+	for attempt := 1; ; attempt++ {
+		revision := EventRevision{
+			EventID: event.ID,
+			Title:   event.Title,
+			Slug:    event.Slug,
+			Status:  event.Status,
+			Date:    event.Date,
+			Media:   event.Media,
+			Content: event.Content,
+			Tags:    string(tagsJSON),
+			Actor:   actor,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var lastRevision int
+			if err := tx.Model(&EventRevision{}).Where("event_id = ?", event.ID).
+				Select("COALESCE(MAX(revision),0)").Scan(&lastRevision).Error; err != nil {
+				return err
+			}
+			revision.Revision = lastRevision + 1
+			return tx.Create(&revision).Error
+		})
+		if err == nil {
+			return &revision, nil
+		}
+		if !IsDuplicateError(err) || attempt == maxRevisionAttempts {
+			return nil, err
+		}
+	}
+}
+
+// How often creating a revision is attempted when concurrent saves collide
+const maxRevisionAttempts = 5
+
+// EnsureEventRevision creates a baseline revision for an event that has none yet.
+// The event must have its Tags loaded.
+func EnsureEventRevision(db *gorm.DB, event *Event) error {
+	var count int64
+	if err := db.Model(&EventRevision{}).Where("event_id = ?", event.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := CreateEventRevision(db, event, "")
+	return err
+}
+
+// GetEventRevision returns a single revision of an event by revision number
+func GetEventRevision(db *gorm.DB, eventID uint, revision int) (*EventRevision, error) {
+	var rev EventRevision
+	err := db.Where("event_id = ? AND revision = ?", eventID, revision).First(&rev).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
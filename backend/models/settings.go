@@ -54,27 +54,45 @@ func GetOrCreateSettings(db *gorm.DB) (*ProjectSettings, error) {
 }
 
 type MailSettings struct {
-	ID             uint           `json:"id" gorm:"primaryKey"`
-	SMTPHost       string         `json:"smtp_host" gorm:"column:smtp_host"`
-	SMTPPort       int            `json:"smtp_port" gorm:"column:smtp_port;default:587"`
-	SMTPUsername   string         `json:"smtp_username" gorm:"column:smtp_username"`
-	SMTPPassword   string         `json:"smtp_password" gorm:"column:smtp_password"`
-	SMTPEncryption string         `json:"smtp_encryption" gorm:"column:smtp_encryption;default:'tls'"`
-	FromEmail      string         `json:"from_email" gorm:"column:from_email"`
-	FromName       string         `json:"from_name" gorm:"column:from_name"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                  uint           `json:"id" gorm:"primaryKey"`
+	Provider            string         `json:"provider" gorm:"column:provider;default:'smtp'"` // smtp, postmark, sendgrid, mailgun, ses or file
+	SMTPHost            string         `json:"smtp_host" gorm:"column:smtp_host"`
+	SMTPPort            int            `json:"smtp_port" gorm:"column:smtp_port;default:587"`
+	SMTPUsername        string         `json:"smtp_username" gorm:"column:smtp_username"`
+	SMTPPassword        string         `json:"smtp_password" gorm:"column:smtp_password"`
+	SMTPEncryption      string         `json:"smtp_encryption" gorm:"column:smtp_encryption;default:'tls'"`
+	FromEmail           string         `json:"from_email" gorm:"column:from_email"`
+	FromName            string         `json:"from_name" gorm:"column:from_name"`
+	APIKey              string         `json:"api_key" gorm:"column:api_key"`                                 // HTTP provider API key (SES access key ID)
+	APISecret           string         `json:"api_secret" gorm:"column:api_secret"`                           // SES secret access key
+	APIDomain           string         `json:"api_domain" gorm:"column:api_domain"`                           // Mailgun sending domain
+	APIRegion           string         `json:"api_region" gorm:"column:api_region"`                           // SES region
+	APIEndpoint         string         `json:"api_endpoint" gorm:"column:api_endpoint"`                       // overrides the provider's API base URL
+	FilePath            string         `json:"file_path" gorm:"column:file_path"`                             // file provider: directory, or a .mbox file
+	TrackingEnabled     bool           `json:"tracking_enabled" gorm:"column:tracking_enabled;default:false"` // newsletter open and click tracking (opt-in)
+	BounceWebhookSecret string         `json:"bounce_webhook_secret" gorm:"column:bounce_webhook_secret"`     // authenticates bounce and complaint webhooks
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type UpdateMailSettingsRequest struct {
-	SMTPHost       *string `json:"smtp_host"`
-	SMTPPort       *int    `json:"smtp_port"`
-	SMTPUsername   *string `json:"smtp_username"`
-	SMTPPassword   *string `json:"smtp_password"`
-	SMTPEncryption *string `json:"smtp_encryption"`
-	FromEmail      *string `json:"from_email"`
-	FromName       *string `json:"from_name"`
+	SMTPHost                  *string `json:"smtp_host"`
+	SMTPPort                  *int    `json:"smtp_port"`
+	SMTPUsername              *string `json:"smtp_username"`
+	SMTPPassword              *string `json:"smtp_password"`
+	SMTPEncryption            *string `json:"smtp_encryption"`
+	FromEmail                 *string `json:"from_email"`
+	FromName                  *string `json:"from_name"`
+	Provider                  *string `json:"provider"`
+	APIKey                    *string `json:"api_key"`
+	APISecret                 *string `json:"api_secret"`
+	APIDomain                 *string `json:"api_domain"`
+	APIRegion                 *string `json:"api_region"`
+	APIEndpoint               *string `json:"api_endpoint"`
+	FilePath                  *string `json:"file_path"`
+	TrackingEnabled           *bool   `json:"tracking_enabled"`
+	RotateBounceWebhookSecret bool    `json:"rotate_bounce_webhook_secret"` // generate a new bounce webhook secret
 }
 
 // GetOrCreateMailSettings ensures there's always a mail settings record
@@ -88,6 +106,7 @@ func GetOrCreateMailSettings(db *gorm.DB) (*MailSettings, error) {
 	if count == 0 {
 		// Create default settings if none exist
 		settings = MailSettings{
+			Provider:       "smtp",
 			SMTPHost:       "",
 			SMTPPort:       587,
 			SMTPUsername:   "",
@@ -167,6 +167,22 @@ func GetOrCreateMapping(db *gorm.DB, statusDefID uint, themeID string, defaultCa
 	return &mapping, nil
 }
 
+// GetStatusCategories returns the current theme's category IDs for a status name
+func GetStatusCategories(db *gorm.DB, statusName string) ([]string, error) {
+	categories := []string{}
+
+	settings, err := GetOrCreateSettings(db)
+	if err != nil || settings.CurrentThemeID == "" {
+		return categories, err
+	}
+
+	err = db.Model(&StatusCategoryMapping{}).
+		Joins("JOIN event_status_definitions ON event_status_definitions.id = status_category_mappings.status_definition_id").
+		Where("status_category_mappings.theme_id = ? AND event_status_definitions.display_name = ?", settings.CurrentThemeID, statusName).
+		Pluck("status_category_mappings.category_id", &categories).Error
+	return categories, err
+}
+
 // SuggestCategoryForStatus suggests a category based on status name
 func SuggestCategoryForStatus(statusName string, categories []ThemeCategory) string {
 	lower := strings.ToLower(strings.TrimSpace(statusName))
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// UserRole represents the permission level of an admin account
+type UserRole string
+
+const (
+	RoleOwner  UserRole = "owner"  // Full access, including users, mail settings and themes
+	RoleEditor UserRole = "editor" // Can manage events, tags, statuses and newsletters
+	RoleViewer UserRole = "viewer" // Read-only access to the admin interface
+)
+
+// roleRank orders roles so that a higher rank includes the permissions of lower ranks
+var roleRank = map[UserRole]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// User represents an admin account
+type User struct {
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	Username          string         `json:"username" gorm:"not null"` // unique among live accounts regardless of case, see MigrateUsernameIndex
+	Email             string         `json:"email"`
+	PasswordHash      string         `json:"-" gorm:"not null"`
+	Role              UserRole       `json:"role" gorm:"not null;default:'viewer'"`
+	LastLoginAt       *time.Time     `json:"last_login_at"`
+	PasswordChangedAt *time.Time     `json:"password_changed_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type CreateUserRequest struct {
+	Username string   `json:"username" binding:"required"`
+	Email    string   `json:"email"`
+	Password string   `json:"password" binding:"required,min=8"`
+	Role     UserRole `json:"role" binding:"required"`
+}
+
+type UpdateUserRequest struct {
+	Email    *string   `json:"email"`
+	Password *string   `json:"password"`
+	Role     *UserRole `json:"role"`
+}
+
+// IsValidRole checks if a role is one of the known roles
+func IsValidRole(role UserRole) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role grants at least the permissions of required
+func RoleAtLeast(role, required UserRole) bool {
+	return roleRank[role] >= roleRank[required]
+}
+
+// SetPassword hashes and stores a new password for the user
+func (u *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	u.PasswordHash = string(hash)
+	u.PasswordChangedAt = &now
+	return nil
+}
+
+// CheckPassword compares a plain-text password against the stored hash
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// FindUserByUsername finds a user by username (case-insensitive)
+func FindUserByUsername(db *gorm.DB, username string) (*User, error) {
+	var user User
+	err := db.Where("LOWER(username) = ?", strings.ToLower(username)).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// AuthenticateUser returns the user matching the given credentials
+func AuthenticateUser(db *gorm.DB, username, password string) (*User, error) {
+	user, err := FindUserByUsername(db, username)
+	if err != nil {
+		// Compare against a dummy hash so unknown usernames take as long as wrong passwords
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, err
+	}
+
+	if !user.CheckPassword(password) {
+		return nil, fmt.Errorf("invalid password")
+	}
+
+	now := time.Now()
+	user.LastLoginAt = &now
+	db.Model(user).UpdateColumn("last_login_at", now)
+
+	return user, nil
+}
+
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// dummyPasswordHash returns a hash with the same cost as stored passwords
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+// CountOwners returns the number of owner accounts
+func CountOwners(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Model(&User{}).Where("role = ?", RoleOwner).Count(&count).Error
+	return count, err
+}
+
+// MigrateUsernameIndex replaces the case-sensitive unique index on usernames with one
+// on the lowercase username of live accounts, so "Admin" and "admin" cannot both exist
+// and the name of a deleted account can be used again
+func MigrateUsernameIndex(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&User{}, "idx_users_username") {
+		if err := db.Migrator().DropIndex(&User{}, "idx_users_username"); err != nil {
+			return fmt.Errorf("failed to drop username index: %w", err)
+		}
+	}
+	if err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower
+		ON users (LOWER(username)) WHERE deleted_at IS NULL`).Error; err != nil {
+		return fmt.Errorf("failed to create username index: %w", err)
+	}
+	return nil
+}
+
+// SeedInitialOwner creates the first owner account from ADMIN_USERNAME/ADMIN_PASSWORD
+// when no users exist yet, so existing single-login installs keep working.
+func SeedInitialOwner(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&User{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check existing users: %w", err)
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	username := os.Getenv("ADMIN_USERNAME")
+	password := os.Getenv("ADMIN_PASSWORD")
+	if username == "" {
+		username = "admin"
+	}
+	if password == "" {
+		password = "admin"
+	}
+
+	owner := User{
+		Username: username,
+		Role:     RoleOwner,
+	}
+	if err := owner.SetPassword(password); err != nil {
+		return fmt.Errorf("failed to hash owner password: %w", err)
+	}
+
+	if err := db.Create(&owner).Error; err != nil {
+		return fmt.Errorf("failed to create owner account: %w", err)
+	}
+
+	fmt.Printf("Created initial owner account: %s\n", owner.Username)
+	return nil
+}
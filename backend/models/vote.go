@@ -9,7 +9,8 @@ import (
 type Vote struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	EventID   uint           `json:"event_id" gorm:"not null;index"`
-	IPAddress string         `json:"ip_address" gorm:"not null;index"`
+	IPAddress string         `json:"-" gorm:"not null;index"` // keyed hash of the IP address, never the address itself
+	Identity  string         `json:"-" gorm:"index"`          // visitor cookie ("v:") or hashed IP ("ip:"), unique per event
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
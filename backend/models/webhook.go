@@ -0,0 +1,151 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook event types that can be subscribed to
+const (
+	WebhookEventCreated       = "event.created"
+	WebhookEventStatusChanged = "event.status_changed"
+	WebhookEventPublished     = "event.published"
+	WebhookFeedbackSubmitted  = "feedback.submitted"
+	WebhookTest               = "webhook.test" // sent by the test endpoint, always delivered
+)
+
+// WebhookEventTypes lists the event types a webhook can subscribe to
+var WebhookEventTypes = []string{
+	WebhookEventCreated,
+	WebhookEventStatusChanged,
+	WebhookEventPublished,
+	WebhookFeedbackSubmitted,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySending   = "sending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is an outbound HTTP endpoint notified about changelog activity
+type Webhook struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Name       string         `json:"name"`
+	URL        string         `json:"url" gorm:"not null"`
+	Secret     string         `json:"secret,omitempty"`            // HMAC-SHA256 signing key, only returned on create
+	EventTypes string         `json:"-" gorm:"type:text;not null"` // JSON array of subscribed event types
+	Active     bool           `json:"active" gorm:"default:true"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// WebhookDelivery records a single payload sent (or to be sent) to a webhook
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	WebhookID      uint       `json:"webhook_id" gorm:"not null;index"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        JSONText   `json:"payload" gorm:"type:text"`
+	Status         string     `json:"status" gorm:"not null;index"` // pending, sending, succeeded, failed
+	Attempts       int        `json:"attempts" gorm:"default:0"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body" gorm:"type:text"` // truncated
+	Error          string     `json:"error"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at" gorm:"index"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url" binding:"required"`
+	Secret     string   `json:"secret"` // generated when empty
+	EventTypes []string `json:"event_types" binding:"required"`
+	Active     *bool    `json:"active"`
+}
+
+type UpdateWebhookRequest struct {
+	Name         *string   `json:"name"`
+	URL          *string   `json:"url"`
+	Secret       *string   `json:"secret"`
+	RotateSecret bool      `json:"rotate_secret"` // generate a new secret, returned in the response
+	EventTypes   *[]string `json:"event_types"`
+	Active       *bool     `json:"active"`
+}
+
+// IsValidWebhookEventType checks if an event type can be subscribed to
+func IsValidWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// EventTypeList returns the event types the webhook is subscribed to
+func (w *Webhook) EventTypeList() []string {
+	types := []string{}
+	if w.EventTypes != "" {
+		json.Unmarshal([]byte(w.EventTypes), &types)
+	}
+	return types
+}
+
+// SetEventTypes stores the subscribed event types
+func (w *Webhook) SetEventTypes(types []string) {
+	data, _ := json.Marshal(types)
+	w.EventTypes = string(data)
+}
+
+// Subscribes reports whether the webhook wants deliveries for an event type
+func (w *Webhook) Subscribes(eventType string) bool {
+	if eventType == WebhookTest {
+		return true
+	}
+	for _, t := range w.EventTypeList() {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON includes the decoded event type list in API responses
+func (w Webhook) MarshalJSON() ([]byte, error) {
+	type webhookAlias Webhook
+	return json.Marshal(struct {
+		webhookAlias
+		EventTypes []string `json:"event_types"`
+	}{
+		webhookAlias: webhookAlias(w),
+		EventTypes:   w.EventTypeList(),
+	})
+}
+
+// GetWebhookDeliveries returns paginated deliveries for a webhook, newest first
+func GetWebhookDeliveries(db *gorm.DB, webhookID uint, status string, page, limit int) ([]WebhookDelivery, int64, error) {
+	var deliveries []WebhookDelivery
+	var total int64
+
+	query := db.Model(&WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated records
+	offset := (page - 1) * limit
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error
+	return deliveries, total, err
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"shipshipship/email"
+	"shipshipship/models"
+
+	"gorm.io/gorm"
+)
+
+// How often the bounce maildir is checked for new messages
+const bounceMaildirInterval = time.Minute
+
+// RecordBounces suppresses the addresses of hard bounces and spam complaints.
+// Soft bounces are only logged, the address may accept mail again later.
+// It returns the number of addresses added to or updated on the suppression list.
+func RecordBounces(db *gorm.DB, source string, bounces []email.Bounce) (int, error) {
+	suppressed := 0
+	for _, bounce := range bounces {
+		var reason string
+		switch bounce.Type {
+		case email.BounceHard:
+			reason = models.SuppressionHardBounce
+		case email.BounceComplaint:
+			reason = models.SuppressionComplaint
+		default:
+			log.Printf("Soft bounce for %s from %s: %s", bounce.Email, source, bounce.Detail)
+			continue
+		}
+
+		if _, err := models.SuppressEmail(db, bounce.Email, reason, source, bounce.Detail); err != nil {
+			return suppressed, err
+		}
+		log.Printf("Suppressed %s after %s from %s: %s", bounce.Email, bounce.Type, source, bounce.Detail)
+		suppressed++
+	}
+	return suppressed, nil
+}
+
+// BounceService reads bounce and complaint reports delivered to a local maildir,
+// set with BOUNCE_MAILDIR. Processed messages are moved from new/ to cur/.
+type BounceService struct {
+	db       *gorm.DB
+	maildir  string
+	stopChan chan struct{}
+}
+
+// NewBounceService creates a new bounce service configured from the environment
+func NewBounceService(db *gorm.DB) *BounceService {
+	return &BounceService{
+		db:       db,
+		maildir:  strings.TrimSpace(os.Getenv("BOUNCE_MAILDIR")),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins polling the maildir; it does nothing when no maildir is configured
+func (bs *BounceService) Start() {
+	if bs.maildir == "" {
+		return
+	}
+	fmt.Printf("Bounce service started (maildir %s)\n", bs.maildir)
+
+	bs.processMaildir()
+
+	ticker := time.NewTicker(bounceMaildirInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				bs.processMaildir()
+			case <-bs.stopChan:
+				ticker.Stop()
+				fmt.Println("Bounce service stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the bounce service
+func (bs *BounceService) Stop() {
+	close(bs.stopChan)
+}
+
+// processMaildir parses every message in new/ and moves it to cur/ marked as seen.
+// Messages are left in new/ when recording their bounces fails, so they are retried.
+func (bs *BounceService) processMaildir() {
+	newDir := filepath.Join(bs.maildir, "new")
+	curDir := filepath.Join(bs.maildir, "cur")
+
+	entries, err := os.ReadDir(newDir)
+	if err != nil {
+		fmt.Printf("Warning: Failed to read bounce maildir: %v\n", err)
+		return
+	}
+	if err := os.MkdirAll(curDir, 0755); err != nil {
+		fmt.Printf("Warning: Failed to create %s: %v\n", curDir, err)
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(newDir, entry.Name())
+
+		bounces, err := parseBounceFile(path)
+		if err != nil {
+			// Not a readable message; move it along so it isn't parsed every minute
+			fmt.Printf("Warning: Failed to parse bounce message %s: %v\n", entry.Name(), err)
+		} else if _, err := RecordBounces(bs.db, "maildir", bounces); err != nil {
+			fmt.Printf("Warning: Failed to record bounces from %s: %v\n", entry.Name(), err)
+			continue
+		}
+
+		if err := os.Rename(path, filepath.Join(curDir, entry.Name()+":2,S")); err != nil {
+			fmt.Printf("Warning: Failed to move bounce message %s: %v\n", entry.Name(), err)
+		}
+	}
+}
+
+func parseBounceFile(path string) ([]email.Bounce, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return email.ParseBounceMessage(file)
+}
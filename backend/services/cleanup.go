@@ -121,7 +121,7 @@ func (cs *CleanupService) runCleanup() {
 	fmt.Printf("Cleanup complete: %d deleted, %d kept, %d errors\n", deletedCount, skippedCount, errorCount)
 }
 
-// getReferencedFiles retrieves all filenames referenced in events
+// getReferencedFiles retrieves all filenames referenced in events and their revisions
 func (cs *CleanupService) getReferencedFiles() map[string]bool {
 	referenced := make(map[string]bool)
 
@@ -136,6 +136,17 @@ func (cs *CleanupService) getReferencedFiles() map[string]bool {
 		return referenced
 	}
 
+	// Earlier revisions keep referencing media removed from the live event
+	var revisionResults []struct {
+		Media   string
+		Content string
+	}
+	if err := cs.db.Table("event_revisions").Select("media, content").Find(&revisionResults).Error; err != nil {
+		fmt.Printf("Error querying event revisions: %v\n", err)
+	} else {
+		results = append(results, revisionResults...)
+	}
+
 	// Extract filenames from Media JSON arrays
 	for _, result := range results {
 		// Parse Media field (JSON array of URLs)
@@ -176,6 +187,16 @@ func (cs *CleanupService) getReferencedFiles() map[string]bool {
 		}
 	}
 
+	// Reaction icons
+	var iconURLs []string
+	if err := cs.db.Table("reaction_definitions").Where("icon_url <> ''").Pluck("icon_url", &iconURLs).Error; err == nil {
+		for _, iconURL := range iconURLs {
+			if filename := cs.extractFilenameFromURL(iconURL); filename != "" {
+				referenced[filename] = true
+			}
+		}
+	}
+
 	return referenced
 }
 
@@ -1,15 +1,17 @@
 package services
 
 import (
+	"errors"
 	"fmt"
-	"net/smtp"
-	"strings"
 
 	"shipshipship/database"
+	"shipshipship/email"
 	"shipshipship/models"
-	"shipshipship/utils"
 )
 
+// ErrRecipientSuppressed is returned when sending to an address on the suppression list
+var ErrRecipientSuppressed = errors.New("recipient address is suppressed after a bounce or complaint")
+
 type EmailService struct {
 	mailSettings *models.MailSettings
 }
@@ -19,8 +21,35 @@ func NewEmailService() *EmailService {
 	return &EmailService{}
 }
 
-// SendEmail sends an email to a single recipient
-func (es *EmailService) SendEmail(to, subject, htmlContent string) error {
+// SendEmail sends an email to a single recipient. When listUnsubscribeURL is set the
+// List-Unsubscribe headers for one-click unsubscribe (RFC 8058) are added.
+func (es *EmailService) SendEmail(to, subject, htmlContent, listUnsubscribeURL string) error {
+	msg := &email.Message{
+		To:      to,
+		Subject: subject,
+		HTML:    htmlContent,
+	}
+	if listUnsubscribeURL != "" {
+		msg.Headers = map[string]string{
+			"List-Unsubscribe":      fmt.Sprintf("<%s>", listUnsubscribeURL),
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+	return es.SendMessage(msg)
+}
+
+// SendMessage sends a message through the configured mail provider.
+// The sender is filled in from the mail settings unless already set.
+// Messages to suppressed addresses are not sent and return ErrRecipientSuppressed.
+func (es *EmailService) SendMessage(msg *email.Message) error {
+	suppressed, err := models.IsEmailSuppressed(database.GetDB(), msg.To)
+	if err != nil {
+		return fmt.Errorf("failed to check suppression list: %v", err)
+	}
+	if suppressed {
+		return ErrRecipientSuppressed
+	}
+
 	// Get mail settings
 	if es.mailSettings == nil {
 		db := database.GetDB()
@@ -32,42 +61,29 @@ func (es *EmailService) SendEmail(to, subject, htmlContent string) error {
 	}
 
 	// Validate settings
-	if es.mailSettings.SMTPHost == "" || es.mailSettings.FromEmail == "" {
-		return fmt.Errorf("SMTP host and from email must be configured")
+	if es.mailSettings.FromEmail == "" {
+		return fmt.Errorf("from email must be configured")
 	}
 
-	// Prepare email content
-	fromName := es.mailSettings.FromName
-	if fromName == "" {
-		fromName = "ShipShipShip"
+	transport, err := email.NewTransport(es.mailSettings)
+	if err != nil {
+		return err
 	}
 
-	from := fmt.Sprintf("%s <%s>", fromName, es.mailSettings.FromEmail)
-
-	// Create email message
-	message := fmt.Sprintf("From: %s\r\n", from)
-	message += fmt.Sprintf("To: %s\r\n", to)
-	message += fmt.Sprintf("Subject: %s\r\n", subject)
-	message += "Content-Type: text/html; charset=UTF-8\r\n"
-	message += "\r\n"
-	message += htmlContent
-
-	// Determine authentication
-	var auth smtp.Auth
-	if es.mailSettings.SMTPUsername != "" {
-		auth = smtp.PlainAuth("", es.mailSettings.SMTPUsername, es.mailSettings.SMTPPassword, es.mailSettings.SMTPHost)
+	msg.FromEmail = es.mailSettings.FromEmail
+	if msg.FromName == "" {
+		msg.FromName = es.mailSettings.FromName
+	}
+	if msg.FromName == "" {
+		// Fall back to the project name
+		msg.FromName = "ShipShipShip"
+		if projectSettings, err := models.GetOrCreateSettings(database.GetDB()); err == nil && projectSettings.Title != "" {
+			msg.FromName = projectSettings.Title
+		}
 	}
 
-	// Send email based on encryption type
-	addr := fmt.Sprintf("%s:%d", es.mailSettings.SMTPHost, es.mailSettings.SMTPPort)
-
-	switch strings.ToLower(es.mailSettings.SMTPEncryption) {
-	case "ssl":
-		return utils.SendMailWithSSL(addr, auth, es.mailSettings.FromEmail, []string{to}, []byte(message))
-	case "tls":
-		return utils.SendMailWithTLS(addr, auth, es.mailSettings.FromEmail, []string{to}, []byte(message))
-	default:
-		// No encryption
-		return smtp.SendMail(addr, auth, es.mailSettings.FromEmail, []string{to}, []byte(message))
+	if err := msg.Prepare(); err != nil {
+		return err
 	}
+	return transport.Send(msg)
 }
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"shipshipship/email"
+	"shipshipship/middleware"
+	"shipshipship/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// How often the queue looks for due jobs when nothing wakes it up
+	emailQueuePollInterval = 5 * time.Second
+	// Jobs are marked failed after this many attempts
+	emailJobMaxAttempts = 5
+	// Delay before the first retry of a job, doubled on every further attempt
+	emailJobBaseBackoff = time.Minute
+	// Defaults for EMAIL_WORKERS and EMAIL_RATE_PER_MINUTE
+	defaultEmailWorkers       = 4
+	defaultEmailRatePerMinute = 120
+)
+
+// emailQueueWake lets enqueuers start the queue without waiting for the next poll
+var emailQueueWake = make(chan struct{}, 1)
+
+// NotifyEmailQueue wakes the queue so newly enqueued jobs are picked up right away
+func NotifyEmailQueue() {
+	select {
+	case emailQueueWake <- struct{}{}:
+	default:
+	}
+}
+
+// EmailQueueService sends queued email jobs with a pool of workers.
+// Concurrency is set by EMAIL_WORKERS and throughput by EMAIL_RATE_PER_MINUTE (0 = unlimited).
+type EmailQueueService struct {
+	db            *gorm.DB
+	workers       int
+	ratePerMinute int
+	jobs          chan models.EmailJob
+	stopChan      chan struct{}
+}
+
+// NewEmailQueueService creates a new email queue service configured from the environment
+func NewEmailQueueService(db *gorm.DB) *EmailQueueService {
+	return &EmailQueueService{
+		db:            db,
+		workers:       envInt("EMAIL_WORKERS", defaultEmailWorkers, 1),
+		ratePerMinute: envInt("EMAIL_RATE_PER_MINUTE", defaultEmailRatePerMinute, 0),
+		jobs:          make(chan models.EmailJob),
+		stopChan:      make(chan struct{}),
+	}
+}
+
+// Start launches the dispatcher and worker pool
+func (qs *EmailQueueService) Start() {
+	fmt.Printf("Email queue started (%d workers, %d emails/minute)\n", qs.workers, qs.ratePerMinute)
+
+	// Jobs interrupted by a restart are sent again
+	qs.db.Model(&models.EmailJob{}).
+		Where("status = ?", models.EmailJobSending).
+		Update("status", models.EmailJobQueued)
+
+	var throttle <-chan time.Time
+	if qs.ratePerMinute > 0 {
+		throttle = time.NewTicker(time.Minute / time.Duration(qs.ratePerMinute)).C
+	}
+
+	for i := 0; i < qs.workers; i++ {
+		go qs.worker(throttle)
+	}
+	go qs.dispatch()
+}
+
+// Stop stops dispatching new jobs; jobs already handed to workers finish
+func (qs *EmailQueueService) Stop() {
+	close(qs.stopChan)
+}
+
+// dispatch hands due jobs to the workers until stopped
+func (qs *EmailQueueService) dispatch() {
+	ticker := time.NewTicker(emailQueuePollInterval)
+	defer ticker.Stop()
+	defer close(qs.jobs)
+
+	for {
+		if !qs.dispatchDue() {
+			fmt.Println("Email queue stopped")
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-emailQueueWake:
+		case <-qs.stopChan:
+			fmt.Println("Email queue stopped")
+			return
+		}
+	}
+}
+
+// dispatchDue claims and hands out all jobs that are due. Returns false when stopped.
+func (qs *EmailQueueService) dispatchDue() bool {
+	for {
+		var due []models.EmailJob
+		if err := qs.db.Where("status = ? AND next_attempt_at <= ?", models.EmailJobQueued, time.Now()).
+			Order("id ASC").Limit(qs.workers * 10).Find(&due).Error; err != nil {
+			log.Printf("Email queue: failed to query due jobs: %v", err)
+			return true
+		}
+		if len(due) == 0 {
+			return true
+		}
+
+		for _, job := range due {
+			// Claim the job so it is never handed out twice
+			result := qs.db.Model(&models.EmailJob{}).
+				Where("id = ? AND status = ?", job.ID, models.EmailJobQueued).
+				Update("status", models.EmailJobSending)
+			if result.Error != nil || result.RowsAffected == 0 {
+				continue
+			}
+
+			select {
+			case qs.jobs <- job:
+			case <-qs.stopChan:
+				// Give the claimed job back for the next start
+				qs.db.Model(&models.EmailJob{}).Where("id = ?", job.ID).Update("status", models.EmailJobQueued)
+				return false
+			}
+		}
+	}
+}
+
+// worker sends jobs from the dispatcher, waiting on the throttle before each send
+func (qs *EmailQueueService) worker(throttle <-chan time.Time) {
+	for job := range qs.jobs {
+		if throttle != nil {
+			<-throttle
+		}
+		qs.processJob(job)
+	}
+}
+
+// processJob sends one email and records the outcome on the job
+func (qs *EmailQueueService) processJob(job models.EmailJob) {
+	var campaign models.EmailCampaign
+	if err := qs.db.First(&campaign, job.CampaignID).Error; err != nil {
+		qs.db.Model(&job).Updates(map[string]interface{}{
+			"status":     models.EmailJobFailed,
+			"last_error": "campaign no longer exists",
+		})
+		return
+	}
+
+	qs.db.Model(&models.EmailCampaign{}).
+		Where("id = ? AND status = ?", campaign.ID, models.CampaignStatusQueued).
+		Update("status", models.CampaignStatusSending)
+
+	content, oneClickURL := PersonalizeNewsletterContent(campaign.Content, campaign.BaseURL, job.Email, job.ID)
+	err := NewEmailService().SendEmail(job.Email, campaign.Subject, content, oneClickURL)
+
+	job.Attempts++
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": job.Attempts}
+
+	switch {
+	case err == nil:
+		updates["status"] = models.EmailJobSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	case errors.Is(err, ErrRecipientSuppressed):
+		// The address bounced or complained after the campaign was queued
+		updates["status"] = models.EmailJobSuppressed
+		updates["last_error"] = err.Error()
+	case errors.Is(err, email.ErrInvalidMessage):
+		// Malformed address or header, sending it again fails the same way
+		updates["status"] = models.EmailJobFailed
+		updates["last_error"] = err.Error()
+	case email.IsRecipientRejected(err):
+		// The server or provider rejected the recipient; retrying will not help
+		updates["status"] = models.EmailJobBounced
+		updates["last_error"] = err.Error()
+		suppressRejectedRecipient(qs.db, job.Email, err)
+	case email.IsPermanentError(err):
+		// Usually the mail settings (credentials, sender); retry the campaign once fixed
+		updates["status"] = models.EmailJobFailed
+		updates["last_error"] = err.Error()
+	case job.Attempts >= emailJobMaxAttempts:
+		updates["status"] = models.EmailJobFailed
+		updates["last_error"] = err.Error()
+	default:
+		updates["status"] = models.EmailJobQueued
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = now.Add(emailJobBackoff(job.Attempts))
+	}
+
+	if err := qs.db.Model(&job).Updates(updates).Error; err != nil {
+		log.Printf("Email queue: failed to update job %d: %v", job.ID, err)
+	}
+
+	completeCampaignIfFinished(qs.db, campaign.ID)
+}
+
+// suppressRejectedRecipient suppresses an address the SMTP server refused at RCPT TO
+// with a bad-mailbox status (5.1.x). Other refusals may come from the relay rather than
+// the address, so they are only logged; HTTP providers report bounces through their
+// webhooks instead.
+func suppressRejectedRecipient(db *gorm.DB, address string, err error) {
+	var smtpErr *email.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Command != "RCPT TO" {
+		return
+	}
+	if !strings.HasPrefix(smtpErr.EnhancedCode(), "5.1.") {
+		log.Printf("Email queue: %s rejected without a mailbox status, not suppressing: %v", address, smtpErr.Err)
+		return
+	}
+	if _, err := RecordBounces(db, "smtp", []email.Bounce{{Email: address, Type: email.BounceHard, Detail: smtpErr.Err.Error()}}); err != nil {
+		log.Printf("Email queue: failed to suppress %s: %v", address, err)
+	}
+}
+
+// EnqueueCampaign stores a campaign and one queued job per recipient, then wakes the queue.
+// Open and click tracking is added to the content when enabled in the mail settings.
+func EnqueueCampaign(db *gorm.DB, campaign *models.EmailCampaign, recipients []string) error {
+	campaign.Status = models.CampaignStatusQueued
+	campaign.Total = len(recipients)
+
+	if settings, err := models.GetOrCreateMailSettings(db); err == nil && settings.TrackingEnabled && campaign.BaseURL != "" {
+		campaign.Content = email.InjectTracking(campaign.Content, campaign.BaseURL)
+		campaign.Tracked = true
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(campaign).Error; err != nil {
+			return err
+		}
+
+		// The newsletter history entry collects open and click counts while sending
+		history := models.NewsletterHistory{
+			CampaignID: &campaign.ID,
+			EventID:    campaign.EventID,
+			Subject:    campaign.Subject,
+			Content:    campaign.Content,
+			Status:     "sending",
+			// Replaced with the number of emails actually sent once the campaign completes
+			RecipientCount: len(recipients),
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		jobs := make([]models.EmailJob, len(recipients))
+		for i, email := range recipients {
+			jobs[i] = models.EmailJob{
+				CampaignID:    campaign.ID,
+				Email:         email,
+				Status:        models.EmailJobQueued,
+				NextAttemptAt: now,
+			}
+		}
+		return tx.CreateInBatches(jobs, 500).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	NotifyEmailQueue()
+	return nil
+}
+
+// RetryFailedCampaignJobs re-queues the failed jobs of a campaign. Bounced and suppressed jobs are not retried.
+func RetryFailedCampaignJobs(db *gorm.DB, campaignID uint) (int64, error) {
+	result := db.Model(&models.EmailJob{}).
+		Where("campaign_id = ? AND status = ?", campaignID, models.EmailJobFailed).
+		Updates(map[string]interface{}{
+			"status":          models.EmailJobQueued,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		db.Model(&models.EmailCampaign{}).Where("id = ?", campaignID).
+			Updates(map[string]interface{}{"status": models.CampaignStatusSending, "completed_at": nil})
+		NotifyEmailQueue()
+	}
+	return result.RowsAffected, nil
+}
+
+// completeCampaignIfFinished marks a campaign completed once no job is pending and
+// records it in the event's email history and publication record.
+func completeCampaignIfFinished(db *gorm.DB, campaignID uint) {
+	progress, err := models.GetCampaignProgress(db, campaignID)
+	if err != nil || !progress.Finished {
+		return
+	}
+
+	now := time.Now()
+	result := db.Model(&models.EmailCampaign{}).
+		Where("id = ? AND status <> ?", campaignID, models.CampaignStatusCompleted).
+		Updates(map[string]interface{}{"status": models.CampaignStatusCompleted, "completed_at": now})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	var campaign models.EmailCampaign
+	if err := db.First(&campaign, campaignID).Error; err != nil {
+		return
+	}
+
+	log.Printf("Campaign %d for event %d completed: %d sent, %d failed, %d bounced, %d suppressed",
+		campaign.ID, campaign.EventID, progress.Sent, progress.Failed, progress.Bounced, progress.Suppressed)
+
+	recordNewsletterSent(db, &campaign, int(progress.Sent), now)
+}
+
+// recordNewsletterSent updates the newsletter history, email history and publication record
+// of the campaign's event
+func recordNewsletterSent(db *gorm.DB, campaign *models.EmailCampaign, sentCount int, sentAt time.Time) {
+	db.Model(&models.NewsletterHistory{}).Where("campaign_id = ?", campaign.ID).Updates(map[string]interface{}{
+		"status":          "sent",
+		"recipient_count": sentCount,
+		"sent_at":         sentAt,
+	})
+
+	// A campaign resumed by a retry updates its existing history entry
+	var history models.EventEmailHistory
+	if err := db.Where("campaign_id = ?", campaign.ID).First(&history).Error; err == nil {
+		db.Model(&history).Updates(map[string]interface{}{"subscriber_count": sentCount, "sent_at": sentAt})
+	} else {
+		history = models.EventEmailHistory{
+			EventID:         campaign.EventID,
+			CampaignID:      &campaign.ID,
+			EventStatus:     campaign.EventStatus,
+			EmailSubject:    campaign.Subject,
+			EmailTemplate:   campaign.Template,
+			SubscriberCount: sentCount,
+			SentAt:          sentAt,
+		}
+		if err := db.Create(&history).Error; err != nil {
+			log.Printf("Failed to save email history for campaign %d: %v", campaign.ID, err)
+		}
+	}
+
+	// Update or create publication record for backward compatibility
+	var publication models.EventPublication
+	err := db.Where("event_id = ?", campaign.EventID).First(&publication).Error
+	if err == gorm.ErrRecordNotFound {
+		publication = models.EventPublication{
+			EventID:         campaign.EventID,
+			EmailSent:       true,
+			EmailSubject:    campaign.Subject,
+			EmailContent:    campaign.Content,
+			EmailTemplate:   campaign.Template,
+			EmailSentAt:     &sentAt,
+			SubscriberCount: sentCount,
+		}
+		if err := db.Create(&publication).Error; err != nil {
+			log.Printf("Failed to create publication record for campaign %d: %v", campaign.ID, err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to query publication record: %v", err)
+		return
+	}
+
+	updates := map[string]interface{}{
+		"email_sent":       true,
+		"email_subject":    campaign.Subject,
+		"email_content":    campaign.Content,
+		"email_template":   campaign.Template,
+		"email_sent_at":    &sentAt,
+		"subscriber_count": sentCount,
+	}
+	if err := db.Model(&publication).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update publication record for campaign %d: %v", campaign.ID, err)
+	}
+}
+
+// PersonalizeNewsletterContent fills in the recipient's unsubscribe and preferences links
+// (BaseURL, not ProjectURL) and tracking token, and returns the content with the one-click
+// URL for the List-Unsubscribe header
+func PersonalizeNewsletterContent(content, baseURL, recipient string, jobID uint) (string, string) {
+	unsubscribeURL, oneClickURL := NewsletterUnsubscribeURLs(baseURL, recipient)
+	content = strings.ReplaceAll(content, "{{unsubscribe_url}}", unsubscribeURL)
+	content = strings.ReplaceAll(content, "{{preferences_url}}", NewsletterPreferencesURL(baseURL, recipient))
+	if strings.Contains(content, email.TrackingTokenPlaceholder) {
+		content = strings.ReplaceAll(content, email.TrackingTokenPlaceholder, newsletterTrackingToken(jobID))
+	}
+	return content, oneClickURL
+}
+
+// newsletterTrackingToken returns the signed token identifying one recipient of a campaign
+func newsletterTrackingToken(jobID uint) string {
+	token, err := middleware.GenerateLinkToken(middleware.TokenPurposeNewsletterTracking, strconv.FormatUint(uint64(jobID), 10), 0)
+	if err != nil {
+		log.Printf("Failed to sign tracking token for job %d: %v", jobID, err)
+		return ""
+	}
+	return url.QueryEscape(token)
+}
+
+// NewsletterPreferencesURL returns the signed preference center link of a subscriber
+func NewsletterPreferencesURL(baseURL, email string) string {
+	token, err := middleware.GenerateLinkToken(middleware.TokenPurposeNewsletterPreferences, email, 0)
+	if err != nil {
+		log.Printf("Failed to sign preferences token for %s: %v", email, err)
+		return baseURL + "/newsletter/preferences"
+	}
+	return fmt.Sprintf("%s/newsletter/preferences?token=%s", baseURL, url.QueryEscape(token))
+}
+
+// NewsletterUnsubscribeURLs returns the signed unsubscribe page link and one-click POST
+// endpoint of a subscriber. Both carry the same non-expiring token.
+func NewsletterUnsubscribeURLs(baseURL, email string) (string, string) {
+	token, err := middleware.GenerateLinkToken(middleware.TokenPurposeNewsletterUnsubscribe, email, 0)
+	if err != nil {
+		log.Printf("Failed to sign unsubscribe token for %s: %v", email, err)
+		return baseURL + "/unsubscribe", ""
+	}
+
+	escaped := url.QueryEscape(token)
+	unsubscribeURL := fmt.Sprintf("%s/unsubscribe?token=%s", baseURL, escaped)
+
+	// Mail clients only honor absolute one-click URLs
+	oneClickURL := ""
+	if baseURL != "" {
+		oneClickURL = fmt.Sprintf("%s/api/newsletter/unsubscribe/one-click?token=%s", baseURL, escaped)
+	}
+	return unsubscribeURL, oneClickURL
+}
+
+// emailJobBackoff returns the delay before the next attempt after the given number of attempts
+func emailJobBackoff(attempts int) time.Duration {
+	return emailJobBaseBackoff * time.Duration(1<<uint(attempts-1))
+}
+
+// envInt reads a non-negative integer setting from the environment
+func envInt(name string, fallback, min int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min {
+		fmt.Printf("Warning: Invalid %s=%q, using %d\n", name, value, fallback)
+		return fallback
+	}
+	return parsed
+}
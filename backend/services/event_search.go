@@ -0,0 +1,288 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"shipshipship/email"
+	"shipshipship/markdown"
+	"shipshipship/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrSearchUnavailable is returned when SQLite was built without FTS5
+var ErrSearchUnavailable = errors.New("full-text search is not available, build with -tags sqlite_fts5")
+
+// Markers around matched terms in highlights; replaced by <mark> after escaping
+const (
+	searchMatchStart = "\x02"
+	searchMatchEnd   = "\x03"
+)
+
+// eventSearchEnabled is set once the FTS5 index exists
+var eventSearchEnabled bool
+
+// EventSearchParams filters a full-text search over events
+type EventSearchParams struct {
+	Query      string
+	Statuses   []string // status display names, matched case-insensitively
+	Tags       []string // tag names, matched case-insensitively
+	From       string   // event dates as YYYY-MM-DD, both inclusive; events without a date
+	To         string   // are left out when either is set
+	PublicOnly bool
+	Page       int
+	Limit      int
+}
+
+// EventSearchResult is an event with its relevance and highlighted matches.
+// Highlights are HTML with matched terms wrapped in <mark>.
+type EventSearchResult struct {
+	models.Event
+	Score          float64 `json:"score"` // higher is more relevant
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+// SetupEventSearch creates the FTS5 index over event titles, content and tag names
+// and fills it when it is new or out of step with the events table
+func SetupEventSearch(db *gorm.DB) error {
+	err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS events_fts USING fts5(
+		title, content, tags, tokenize = 'unicode61 remove_diacritics 2'
+	)`).Error
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSearchUnavailable, err)
+	}
+
+	// The table may exist from a build with FTS5 while this one lacks it
+	var indexed, events int64
+	if err := db.Table("events_fts").Count(&indexed).Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrSearchUnavailable, err)
+	}
+	if err := db.Model(&models.Event{}).Count(&events).Error; err != nil {
+		return err
+	}
+	eventSearchEnabled = true
+	if indexed == events {
+		return nil
+	}
+	return RebuildEventSearchIndex(db)
+}
+
+// RebuildEventSearchIndex reindexes every event
+func RebuildEventSearchIndex(db *gorm.DB) error {
+	if !eventSearchEnabled {
+		return ErrSearchUnavailable
+	}
+
+	var events []models.Event
+	if err := db.Preload("Tags").Find(&events).Error; err != nil {
+		return err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM events_fts").Error; err != nil {
+			return err
+		}
+		for i := range events {
+			if err := insertSearchRow(tx, &events[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		fmt.Printf("Indexed %d events for search\n", len(events))
+	}
+	return err
+}
+
+// IndexEvent updates the search index for an event after it was created or edited,
+// or removes it when the event no longer exists
+func IndexEvent(db *gorm.DB, eventID uint) error {
+	if !eventSearchEnabled {
+		return nil
+	}
+
+	var event models.Event
+	err := db.Preload("Tags").First(&event, eventID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return RemoveEventFromIndex(db, eventID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM events_fts WHERE rowid = ?", eventID).Error; err != nil {
+			return err
+		}
+		return insertSearchRow(tx, &event)
+	})
+}
+
+// IndexEvents updates the search index for several events, e.g. after a tag was renamed
+func IndexEvents(db *gorm.DB, eventIDs []uint) error {
+	for _, id := range eventIDs {
+		if err := IndexEvent(db, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveEventFromIndex drops a deleted event from the search index
+func RemoveEventFromIndex(db *gorm.DB, eventID uint) error {
+	if !eventSearchEnabled {
+		return nil
+	}
+	return db.Exec("DELETE FROM events_fts WHERE rowid = ?", eventID).Error
+}
+
+func insertSearchRow(tx *gorm.DB, event *models.Event) error {
+	tagNames := make([]string, len(event.Tags))
+	for i, tag := range event.Tags {
+		tagNames[i] = tag.Name
+	}
+	return tx.Exec("INSERT INTO events_fts (rowid, title, content, tags) VALUES (?, ?, ?, ?)",
+		event.ID, searchableText(event.Title), searchableText(email.HTMLToText(markdown.ToHTML(event.Content))), searchableText(strings.Join(tagNames, " "))).Error
+}
+
+// SearchEvents runs a ranked full-text search. Title matches weigh more than tag matches,
+// which weigh more than content matches. It returns one page of results and the total.
+func SearchEvents(db *gorm.DB, params EventSearchParams) ([]EventSearchResult, int64, error) {
+	if !eventSearchEnabled {
+		return nil, 0, ErrSearchUnavailable
+	}
+
+	match := searchMatchExpression(params.Query)
+	if match == "" {
+		return []EventSearchResult{}, 0, nil
+	}
+
+	query := db.Table("events_fts").
+		Joins("JOIN events ON events.id = events_fts.rowid AND events.deleted_at IS NULL").
+		Where("events_fts MATCH ?", match)
+	if params.PublicOnly {
+		query = query.Where("events.is_public = ?", true)
+	}
+	if len(params.Statuses) > 0 {
+		query = query.Where("LOWER(events.status) IN ?", lowerAll(params.Statuses))
+	}
+	if len(params.Tags) > 0 {
+		query = query.Where(`EXISTS (SELECT 1 FROM event_tags JOIN tags ON tags.id = event_tags.tag_id
+			WHERE event_tags.event_id = events.id AND LOWER(tags.name) IN ?)`, lowerAll(params.Tags))
+	}
+	// Event dates are YYYY-MM-DD, optionally followed by a time
+	if params.From != "" || params.To != "" {
+		query = query.Where("events.date <> ''")
+	}
+	if params.From != "" {
+		query = query.Where("SUBSTR(events.date, 1, 10) >= ?", params.From)
+	}
+	if params.To != "" {
+		query = query.Where("SUBSTR(events.date, 1, 10) <= ?", params.To)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		ID             uint
+		Score          float64
+		TitleHighlight string
+		Snippet        string
+	}
+	err := query.Select(
+		"events.id AS id, -bm25(events_fts, 10.0, 1.0, 5.0) AS score, "+
+			"highlight(events_fts, 0, ?, ?) AS title_highlight, "+
+			"snippet(events_fts, 1, ?, ?, '…', 24) AS snippet",
+		searchMatchStart, searchMatchEnd, searchMatchStart, searchMatchEnd,
+	).
+		Order("score DESC").
+		Limit(params.Limit).
+		Offset((params.Page - 1) * params.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var events []models.Event
+	if len(ids) > 0 {
+		if err := db.Preload("Tags").Find(&events, ids).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+	byID := make(map[uint]models.Event, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+	}
+
+	results := make([]EventSearchResult, 0, len(rows))
+	for _, row := range rows {
+		event, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, EventSearchResult{
+			Event:          event,
+			Score:          row.Score,
+			TitleHighlight: highlightHTML(row.TitleHighlight),
+			Snippet:        highlightHTML(row.Snippet),
+		})
+	}
+	return results, total, nil
+}
+
+// searchMatchExpression turns user input into an FTS5 query that matches all words,
+// the last one as a prefix so results show up while typing. Words are quoted so
+// FTS5 operators and punctuation in the input have no special meaning.
+func searchMatchExpression(input string) string {
+	var terms []string
+	for _, word := range strings.Fields(input) {
+		if !strings.ContainsFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+			continue
+		}
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	terms[len(terms)-1] += "*"
+	return strings.Join(terms, " ")
+}
+
+// highlightHTML escapes a highlight or snippet and turns the match markers into <mark>
+func highlightHTML(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = strings.ReplaceAll(escaped, searchMatchStart, "<mark>")
+	return strings.ReplaceAll(escaped, searchMatchEnd, "</mark>")
+}
+
+// searchableText collapses whitespace and drops control characters, which would
+// otherwise clash with the highlight markers
+func searchableText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(value)
+	}
+	return lowered
+}
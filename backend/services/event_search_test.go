@@ -0,0 +1,192 @@
+//go:build sqlite_fts5
+
+package services
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"shipshipship/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupSearchDB creates a database with the search index and the given events
+func setupSearchDB(t *testing.T, events []models.Event) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Event{}, &models.Tag{}); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	for i := range events {
+		if err := db.Create(&events[i]).Error; err != nil {
+			t.Fatalf("creating event: %v", err)
+		}
+	}
+	if err := SetupEventSearch(db); err != nil {
+		t.Fatalf("setting up search: %v", err)
+	}
+	t.Cleanup(func() { eventSearchEnabled = false })
+	return db
+}
+
+func searchTitles(t *testing.T, db *gorm.DB, params EventSearchParams) []string {
+	t.Helper()
+	if params.Limit == 0 {
+		params.Page, params.Limit = 1, 20
+	}
+	results, total, err := SearchEvents(db, params)
+	if err != nil {
+		t.Fatalf("searching %q: %v", params.Query, err)
+	}
+	if int(total) < len(results) {
+		t.Errorf("total = %d, fewer than the %d results", total, len(results))
+	}
+	titles := make([]string, len(results))
+	for i, result := range results {
+		titles[i] = result.Title
+	}
+	return titles
+}
+
+func TestSearchEventsRanking(t *testing.T) {
+	db := setupSearchDB(t, []models.Event{
+		{Title: "Faster exports", Slug: "exports", Status: "Released", IsPublic: true,
+			Content: "Exports now mention **dark** colors once."},
+		{Title: "Dark mode", Slug: "dark-mode", Status: "Released", IsPublic: true,
+			Content: "The whole app follows the system theme."},
+		{Title: "New icons", Slug: "icons", Status: "Released", IsPublic: true,
+			Content: "Redrawn icons.", Tags: []models.Tag{{Name: "dark", Color: "#000000"}}},
+	})
+
+	// Title matches weigh most, then tags, then content
+	got := searchTitles(t, db, EventSearchParams{Query: "dark"})
+	want := []string{"Dark mode", "New icons", "Faster exports"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+
+	// The last word matches as a prefix while typing
+	if got := searchTitles(t, db, EventSearchParams{Query: "expo"}); len(got) != 1 || got[0] != "Faster exports" {
+		t.Errorf("prefix results = %q, want [Faster exports]", got)
+	}
+
+	// FTS5 syntax in the input is taken literally: all words must match, OR included
+	if got := searchTitles(t, db, EventSearchParams{Query: `icons" OR "dark`}); len(got) != 0 {
+		t.Errorf("results with OR = %q, want none", got)
+	}
+	if got := searchTitles(t, db, EventSearchParams{Query: `"icons" dark`}); len(got) != 1 || got[0] != "New icons" {
+		t.Errorf("results with quotes = %q, want [New icons]", got)
+	}
+}
+
+func TestSearchEventsHighlights(t *testing.T) {
+	db := setupSearchDB(t, []models.Event{
+		{Title: "Dark <b>mode</b>", Slug: "dark-mode", Status: "Released", IsPublic: true,
+			Content: "Use `<script>` tags freely, dark themes are supported everywhere."},
+	})
+
+	results, _, err := SearchEvents(db, EventSearchParams{Query: "dark", Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+
+	result := results[0]
+	if want := "<mark>Dark</mark> &lt;b&gt;mode&lt;/b&gt;"; result.TitleHighlight != want {
+		t.Errorf("title highlight = %q, want %q", result.TitleHighlight, want)
+	}
+	if !strings.Contains(result.Snippet, "<mark>dark</mark> themes") {
+		t.Errorf("snippet = %q, want the match marked", result.Snippet)
+	}
+	if strings.Contains(result.Snippet, "<script>") {
+		t.Errorf("snippet = %q, contains unescaped markup", result.Snippet)
+	}
+	if result.Score <= 0 {
+		t.Errorf("score = %v, want it positive", result.Score)
+	}
+}
+
+func TestSearchEventsFilters(t *testing.T) {
+	db := setupSearchDB(t, []models.Event{
+		{Title: "Release notes January", Slug: "january", Status: "Released", Date: "2024-01-15", IsPublic: true,
+			Tags: []models.Tag{{Name: "UI", Color: "#000000"}}},
+		{Title: "Release notes March", Slug: "march", Status: "Released", Date: "2024-03-31T18:30", IsPublic: true},
+		{Title: "Release notes planned", Slug: "planned", Status: "Upcoming", Date: "2024-06-01", IsPublic: true},
+		{Title: "Release notes undated", Slug: "undated", Status: "Released", IsPublic: true},
+		{Title: "Release notes hidden", Slug: "hidden", Status: "Released", Date: "2024-02-01", IsPublic: true},
+	})
+	// is_public defaults to true on create
+	db.Model(&models.Event{}).Where("slug = ?", "hidden").Update("is_public", false)
+
+	tests := []struct {
+		name   string
+		params EventSearchParams
+		want   []string
+	}{
+		{"public only", EventSearchParams{PublicOnly: true},
+			[]string{"Release notes January", "Release notes March", "Release notes planned", "Release notes undated"}},
+		{"status", EventSearchParams{Statuses: []string{"upcoming"}},
+			[]string{"Release notes planned"}},
+		{"tag", EventSearchParams{Tags: []string{"ui"}},
+			[]string{"Release notes January"}},
+		{"event date range", EventSearchParams{From: "2024-02-01", To: "2024-03-31"},
+			[]string{"Release notes hidden", "Release notes March"}},
+		{"event date from", EventSearchParams{From: "2024-03-01", PublicOnly: true},
+			[]string{"Release notes March", "Release notes planned"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Query = "release notes"
+			got := searchTitles(t, db, tt.params)
+			if strings.Join(sorted(got), "|") != strings.Join(sorted(tt.want), "|") {
+				t.Errorf("results = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchEventsIndexUpdates(t *testing.T) {
+	db := setupSearchDB(t, []models.Event{
+		{Title: "Old title", Slug: "event", Status: "Released", IsPublic: true},
+	})
+
+	var event models.Event
+	db.First(&event)
+	db.Model(&event).Update("title", "Renamed entry")
+	if err := IndexEvent(db, event.ID); err != nil {
+		t.Fatalf("indexing: %v", err)
+	}
+	if got := searchTitles(t, db, EventSearchParams{Query: "old"}); len(got) != 0 {
+		t.Errorf("results for the old title = %q, want none", got)
+	}
+	if got := searchTitles(t, db, EventSearchParams{Query: "renamed"}); len(got) != 1 {
+		t.Errorf("results for the new title = %q, want the event", got)
+	}
+
+	db.Delete(&event)
+	if err := IndexEvent(db, event.ID); err != nil {
+		t.Fatalf("removing from index: %v", err)
+	}
+	if got := searchTitles(t, db, EventSearchParams{Query: "renamed"}); len(got) != 0 {
+		t.Errorf("results after deleting = %q, want none", got)
+	}
+}
+
+func sorted(values []string) []string {
+	out := append([]string(nil), values...)
+	sort.Strings(out)
+	return out
+}
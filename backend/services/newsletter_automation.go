@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
 	"shipshipship/constants"
@@ -18,15 +17,13 @@ import (
 
 // NewsletterAutomationService handles automated newsletter sending
 type NewsletterAutomationService struct {
-	db           *gorm.DB
-	emailService *EmailService
+	db *gorm.DB
 }
 
 // NewNewsletterAutomationService creates a new newsletter automation service
 func NewNewsletterAutomationService() *NewsletterAutomationService {
 	return &NewsletterAutomationService{
-		db:           database.GetDB(),
-		emailService: NewEmailService(),
+		db: database.GetDB(),
 	}
 }
 
@@ -55,11 +52,11 @@ func (nas *NewsletterAutomationService) ProcessStatusChange(eventID uint, oldSta
 	log.Printf("Processing status change for event %d: %s -> %s", eventID, oldStatus, newStatus)
 
 	// Safety check: prevent automation for rapid successive changes
-	// Check if an email was sent for this event in the last 30 seconds
+	// Check if a newsletter was queued for this event in the last 30 seconds
 	var recentEmailCount int64
 	thirtySecondsAgo := time.Now().Add(-30 * time.Second)
-	nas.db.Model(&models.EventEmailHistory{}).
-		Where("event_id = ? AND sent_at > ?", eventID, thirtySecondsAgo).
+	nas.db.Model(&models.EmailCampaign{}).
+		Where("event_id = ? AND created_at > ?", eventID, thirtySecondsAgo).
 		Count(&recentEmailCount)
 
 	if recentEmailCount > 0 {
@@ -105,6 +102,29 @@ func (nas *NewsletterAutomationService) ProcessStatusChange(eventID uint, oldSta
 	return nas.sendAutomatedNewsletter(eventID, newStatus)
 }
 
+// ProcessScheduledPublish sends the newsletter for an event that was just made
+// public by the scheduler. The event's own notify flag replaces the status trigger list.
+func (nas *NewsletterAutomationService) ProcessScheduledPublish(eventID uint) error {
+	var event models.Event
+	if err := nas.db.First(&event, eventID).Error; err != nil {
+		return fmt.Errorf("failed to get event: %v", err)
+	}
+
+	automationSettings, err := models.GetOrCreateAutomationSettings(nas.db)
+	if err != nil {
+		return fmt.Errorf("failed to get automation settings: %v", err)
+	}
+
+	if !automationSettings.Enabled {
+		log.Printf("Newsletter automation is disabled, skipping scheduled publish of event %d", eventID)
+		return nil
+	}
+
+	log.Printf("Triggering newsletter for scheduled publish of event %d", eventID)
+
+	return nas.sendAutomatedNewsletter(eventID, event.Status)
+}
+
 // sendAutomatedNewsletter sends a newsletter for an event based on its status
 func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint, status models.EventStatus) error {
 	// Get the event with tags
@@ -152,98 +172,39 @@ func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint, st
 		return fmt.Errorf("failed to generate email content: %v", err)
 	}
 
-	// Get active newsletter subscribers
-	subscribers, err := models.GetActiveNewsletterSubscribers(nas.db)
+	// Get the subscribers whose topic preferences match this event
+	subscribers, err := models.GetNewsletterSubscribersForEvent(nas.db, &event)
 	if err != nil {
 		return fmt.Errorf("failed to get newsletter subscribers: %v", err)
 	}
 
 	if len(subscribers) == 0 {
-		log.Printf("No active newsletter subscribers found for event %d", eventID)
+		log.Printf("No newsletter subscribers match event %d", eventID)
 		return nil
 	}
 
-	// Send emails to all subscribers
-	sentCount := 0
-	var sendErrors []string
-
-	for _, subscriber := range subscribers {
-		// Personalize unsubscribe URL for each subscriber (use BaseURL, not ProjectURL)
-		unsubscribeURL := fmt.Sprintf("%s/unsubscribe?email=%s", branding.BaseURL, subscriber.Email)
-		if branding.BaseURL == "" {
-			unsubscribeURL = fmt.Sprintf("/unsubscribe?email=%s", subscriber.Email)
-		}
-		personalizedContent := strings.ReplaceAll(content, "{{unsubscribe_url}}", unsubscribeURL)
-
-		err := nas.emailService.SendEmail(subscriber.Email, subject, personalizedContent)
-		if err != nil {
-			errorMsg := fmt.Sprintf("failed to send to %s: %v", subscriber.Email, err)
-			sendErrors = append(sendErrors, errorMsg)
-			log.Printf("Newsletter automation error: %s", errorMsg)
-			continue
-		}
-		sentCount++
-	}
-
-	// Create email history record
-	now := time.Now()
-	historyRecord := &models.EventEmailHistory{
-		EventID:         eventID,
-		EventStatus:     string(status),
-		EmailSubject:    subject,
-		EmailTemplate:   template.Type,
-		SubscriberCount: sentCount,
-		SentAt:          now,
+	recipients := make([]string, len(subscribers))
+	for i, subscriber := range subscribers {
+		recipients[i] = subscriber.Email
 	}
 
-	if err := nas.db.Create(historyRecord).Error; err != nil {
-		log.Printf("Failed to save email history for automated newsletter: %v", err)
-		// Don't return error as emails were already sent
+	// Sending happens in the background email queue
+	campaign := models.EmailCampaign{
+		EventID:     eventID,
+		EventStatus: string(status),
+		Subject:     subject,
+		Content:     content,
+		Template:    template.Type,
+		BaseURL:     branding.BaseURL,
+		Automated:   true,
+		CreatedBy:   "automation",
 	}
-
-	// Update or create publication record for backward compatibility
-	var publication models.EventPublication
-	err = nas.db.Where("event_id = ?", eventID).First(&publication).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Create new publication record
-			publication = models.EventPublication{
-				EventID:         eventID,
-				EmailSent:       true,
-				EmailSubject:    subject,
-				EmailContent:    content,
-				EmailTemplate:   template.Type,
-				EmailSentAt:     &now,
-				SubscriberCount: sentCount,
-			}
-			if err := nas.db.Create(&publication).Error; err != nil {
-				log.Printf("Failed to create publication record for automated newsletter: %v", err)
-			}
-		} else {
-			log.Printf("Failed to query publication record: %v", err)
-		}
-	} else {
-		// Update existing publication record
-		updates := map[string]interface{}{
-			"email_sent":       true,
-			"email_subject":    subject,
-			"email_content":    content,
-			"email_template":   template.Type,
-			"email_sent_at":    &now,
-			"subscriber_count": sentCount,
-		}
-		if err := nas.db.Model(&publication).Updates(updates).Error; err != nil {
-			log.Printf("Failed to update publication record for automated newsletter: %v", err)
-		}
+	if err := EnqueueCampaign(nas.db, &campaign, recipients); err != nil {
+		return fmt.Errorf("failed to queue automated newsletter: %v", err)
 	}
 
-	log.Printf("Automated newsletter sent successfully for event %d: %d/%d emails sent",
-		eventID, sentCount, len(subscribers))
-
-	// Log any send errors but don't fail the operation
-	if len(sendErrors) > 0 {
-		log.Printf("Some emails failed to send: %v", sendErrors)
-	}
+	log.Printf("Automated newsletter queued for event %d: campaign %d with %d recipients",
+		eventID, campaign.ID, len(recipients))
 
 	return nil
 }
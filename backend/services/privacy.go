@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"shipshipship/models"
+
+	"gorm.io/gorm"
+)
+
+// How often identity data past its retention window is purged
+const privacyPurgeInterval = 6 * time.Hour
+
+// PrivacyService removes visitor identities and IP hashes from reactions and votes
+// older than IDENTITY_RETENTION_DAYS (default 90). Their counts are kept.
+type PrivacyService struct {
+	db        *gorm.DB
+	retention time.Duration
+	stopChan  chan struct{}
+}
+
+// NewPrivacyService creates a new privacy service configured from the environment
+func NewPrivacyService(db *gorm.DB) *PrivacyService {
+	return &PrivacyService{
+		db:        db,
+		retention: time.Duration(envInt("IDENTITY_RETENTION_DAYS", 90, 1)) * 24 * time.Hour,
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Start begins the periodic purge
+func (ps *PrivacyService) Start() {
+	fmt.Printf("Privacy service started (retention %d days)\n", int(ps.retention.Hours()/24))
+
+	ps.purge()
+
+	ticker := time.NewTicker(privacyPurgeInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				ps.purge()
+			case <-ps.stopChan:
+				ticker.Stop()
+				fmt.Println("Privacy service stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the privacy service
+func (ps *PrivacyService) Stop() {
+	close(ps.stopChan)
+}
+
+// purge removes identity data from reactions and votes past the retention window
+func (ps *PrivacyService) purge() {
+	purged, err := models.PurgeVisitorIdentities(ps.db, time.Now().Add(-ps.retention))
+	if err != nil {
+		fmt.Printf("Warning: Failed to purge visitor identities: %v\n", err)
+		return
+	}
+	if purged > 0 {
+		fmt.Printf("Purged visitor identities from %d reactions and votes\n", purged)
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"shipshipship/middleware"
+	"shipshipship/models"
+
+	"gorm.io/gorm"
+)
+
+// How often the scheduler checks for due publish/unpublish times
+const schedulerInterval = time.Minute
+
+// SchedulerService flips event visibility at their scheduled publish_at/unpublish_at times
+type SchedulerService struct {
+	db       *gorm.DB
+	stopChan chan struct{}
+}
+
+// NewSchedulerService creates a new scheduler service
+func NewSchedulerService(db *gorm.DB) *SchedulerService {
+	return &SchedulerService{
+		db:       db,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins the periodic schedule check
+func (ss *SchedulerService) Start() {
+	fmt.Println("Scheduler service started")
+
+	// Catch up on anything that came due while the server was down
+	ss.runSchedule()
+
+	ticker := time.NewTicker(schedulerInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				ss.runSchedule()
+			case <-ss.stopChan:
+				ticker.Stop()
+				fmt.Println("Scheduler service stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler service
+func (ss *SchedulerService) Stop() {
+	close(ss.stopChan)
+}
+
+// runSchedule publishes and unpublishes every event whose scheduled time has passed
+func (ss *SchedulerService) runSchedule() {
+	now := time.Now().UTC()
+
+	var toPublish []models.Event
+	if err := ss.db.Where("publish_at IS NOT NULL AND publish_at <= ?", now).Find(&toPublish).Error; err != nil {
+		fmt.Printf("Error querying scheduled publishes: %v\n", err)
+	}
+	for _, event := range toPublish {
+		ss.publishEvent(event)
+	}
+
+	var toUnpublish []models.Event
+	if err := ss.db.Where("unpublish_at IS NOT NULL AND unpublish_at <= ?", now).Find(&toUnpublish).Error; err != nil {
+		fmt.Printf("Error querying scheduled unpublishes: %v\n", err)
+	}
+	for _, event := range toUnpublish {
+		ss.unpublishEvent(event)
+	}
+}
+
+// publishEvent makes a scheduled event public and optionally sends its newsletter
+func (ss *SchedulerService) publishEvent(event models.Event) {
+	// Clear the schedule in the same statement so a slow run never publishes twice
+	result := ss.db.Model(&models.Event{}).
+		Where("id = ? AND publish_at IS NOT NULL", event.ID).
+		Updates(map[string]interface{}{"is_public": true, "publish_at": nil})
+	if result.Error != nil {
+		fmt.Printf("Error publishing scheduled event %d: %v\n", event.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	fmt.Printf("Published scheduled event %d (%s)\n", event.ID, event.Title)
+	middleware.BumpContentVersion()
+	ss.recordChange(event, map[string]interface{}{"is_public": true, "publish_at": nil})
+
+	if !event.IsPublic {
+		ss.db.Preload("Tags").First(&event, event.ID)
+		DispatchWebhookEvent(models.WebhookEventPublished, map[string]interface{}{"event": event})
+	}
+
+	if event.NotifyOnPublish {
+		go func(eventID uint) {
+			automationService := NewNewsletterAutomationService()
+			if err := automationService.ProcessScheduledPublish(eventID); err != nil {
+				fmt.Printf("Newsletter automation error for scheduled event %d: %v\n", eventID, err)
+			}
+		}(event.ID)
+	}
+}
+
+// unpublishEvent hides an event whose scheduled unpublish time has passed
+func (ss *SchedulerService) unpublishEvent(event models.Event) {
+	result := ss.db.Model(&models.Event{}).
+		Where("id = ? AND unpublish_at IS NOT NULL", event.ID).
+		Updates(map[string]interface{}{"is_public": false, "unpublish_at": nil})
+	if result.Error != nil {
+		fmt.Printf("Error unpublishing scheduled event %d: %v\n", event.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	fmt.Printf("Unpublished scheduled event %d (%s)\n", event.ID, event.Title)
+	middleware.BumpContentVersion()
+	ss.recordChange(event, map[string]interface{}{"is_public": false, "unpublish_at": nil})
+}
+
+// recordChange writes an audit entry for a visibility change made by the scheduler
+func (ss *SchedulerService) recordChange(event models.Event, updates map[string]interface{}) {
+	before := map[string]interface{}{
+		"is_public":    event.IsPublic,
+		"publish_at":   event.PublishAt,
+		"unpublish_at": event.UnpublishAt,
+	}
+	after := map[string]interface{}{}
+	for key, value := range before {
+		after[key] = value
+	}
+	for key, value := range updates {
+		after[key] = value
+	}
+
+	if err := models.RecordAudit(ss.db, "scheduler", models.AuditActionUpdate, "event_publication", event.ID, before, after); err != nil {
+		fmt.Printf("Warning: Failed to record audit entry for scheduled event %d: %v\n", event.ID, err)
+	}
+}
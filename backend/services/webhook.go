@@ -0,0 +1,273 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// How often pending webhook retries are checked
+	webhookRetryInterval = 30 * time.Second
+	// Delay before the first retry, doubled on every further attempt
+	webhookBaseBackoff = 30 * time.Second
+	// Longest delay between two attempts
+	webhookMaxBackoff = 6 * time.Hour
+	// Deliveries are marked failed after this many attempts
+	webhookMaxAttempts = 8
+	// Timeout for a single delivery request
+	webhookTimeout = 10 * time.Second
+	// Response bodies are truncated to this many bytes in the delivery log
+	webhookResponseLimit = 2048
+)
+
+// webhookClient is shared by all deliveries
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// WebhookPayload is the JSON body sent to webhook endpoints
+type WebhookPayload struct {
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookService retries failed webhook deliveries with exponential backoff
+type WebhookService struct {
+	db       *gorm.DB
+	stopChan chan struct{}
+}
+
+// NewWebhookService creates a new webhook retry service
+func NewWebhookService(db *gorm.DB) *WebhookService {
+	return &WebhookService{
+		db:       db,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins the periodic retry of pending deliveries
+func (ws *WebhookService) Start() {
+	fmt.Println("Webhook service started")
+
+	// Deliveries interrupted by a restart are retried like any other pending delivery
+	ws.db.Model(&models.WebhookDelivery{}).
+		Where("status = ?", models.WebhookDeliverySending).
+		Update("status", models.WebhookDeliveryPending)
+
+	ticker := time.NewTicker(webhookRetryInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				ws.retryPending()
+			case <-ws.stopChan:
+				ticker.Stop()
+				fmt.Println("Webhook service stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the webhook service
+func (ws *WebhookService) Stop() {
+	close(ws.stopChan)
+}
+
+// retryPending attempts every pending delivery whose next attempt is due
+func (ws *WebhookService) retryPending() {
+	var deliveries []models.WebhookDelivery
+	if err := ws.db.Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+		Order("next_attempt_at ASC").Find(&deliveries).Error; err != nil {
+		fmt.Printf("Error querying pending webhook deliveries: %v\n", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		AttemptWebhookDelivery(ws.db, delivery.ID)
+	}
+}
+
+// DispatchWebhookEvent queues a delivery for every active webhook subscribed to
+// the event type and attempts them in the background.
+func DispatchWebhookEvent(eventType string, data interface{}) {
+	db := database.GetDB()
+
+	var webhooks []models.Webhook
+	if err := db.Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		fmt.Printf("Error querying webhooks for %s: %v\n", eventType, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(eventType) {
+			continue
+		}
+
+		delivery, err := QueueWebhookDelivery(db, &webhook, eventType, data)
+		if err != nil {
+			fmt.Printf("Error queueing webhook %d delivery for %s: %v\n", webhook.ID, eventType, err)
+			continue
+		}
+
+		go AttemptWebhookDelivery(db, delivery.ID)
+	}
+}
+
+// QueueWebhookDelivery stores a pending delivery for a webhook
+func QueueWebhookDelivery(db *gorm.DB, webhook *models.Webhook, eventType string, data interface{}) (*models.WebhookDelivery, error) {
+	payload, err := json.Marshal(WebhookPayload{
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventType:     eventType,
+		Payload:       models.JSONText(payload),
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// AttemptWebhookDelivery sends a pending delivery once and schedules a retry on failure.
+// Deliveries already claimed by another attempt are skipped.
+func AttemptWebhookDelivery(db *gorm.DB, deliveryID uint) *models.WebhookDelivery {
+	// Claim the delivery so the retry loop and an immediate attempt never send it twice
+	result := db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ?", deliveryID, models.WebhookDeliveryPending).
+		Update("status", models.WebhookDeliverySending)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil
+	}
+
+	var delivery models.WebhookDelivery
+	if err := db.First(&delivery, deliveryID).Error; err != nil {
+		return nil
+	}
+
+	var webhook models.Webhook
+	if err := db.First(&webhook, delivery.WebhookID).Error; err != nil {
+		// The webhook was deleted; nothing left to deliver to
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = "webhook no longer exists"
+		delivery.NextAttemptAt = nil
+		db.Save(&delivery)
+		return &delivery
+	}
+
+	delivery.Attempts++
+	statusCode, body, err := sendWebhookRequest(&webhook, &delivery)
+	delivery.ResponseStatus = statusCode
+	delivery.ResponseBody = body
+
+	now := time.Now()
+	switch {
+	case err == nil && statusCode >= 200 && statusCode < 300:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = webhookErrorMessage(statusCode, err)
+		delivery.NextAttemptAt = nil
+	default:
+		next := now.Add(webhookBackoff(delivery.Attempts))
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.Error = webhookErrorMessage(statusCode, err)
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := db.Save(&delivery).Error; err != nil {
+		fmt.Printf("Error saving webhook delivery %d: %v\n", delivery.ID, err)
+	}
+	return &delivery
+}
+
+// sendWebhookRequest posts the delivery payload with its signature headers
+func sendWebhookRequest(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	payload := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ShipShipShip-Webhooks/1.0")
+	req.Header.Set("X-ShipShipShip-Event", delivery.EventType)
+	req.Header.Set("X-ShipShipShip-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-ShipShipShip-Timestamp", timestamp)
+	req.Header.Set("X-ShipShipShip-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	return resp.StatusCode, string(body), nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<payload>".
+// Including the timestamp lets receivers reject replayed deliveries.
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateWebhookSecret returns a random signing secret
+func GenerateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// webhookBackoff returns the delay before the next attempt after the given number of attempts
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return delay
+}
+
+// webhookErrorMessage describes a failed attempt for the delivery log
+func webhookErrorMessage(statusCode int, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("unexpected response status %d", statusCode)
+}
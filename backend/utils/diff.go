@@ -0,0 +1,94 @@
+package utils
+
+// DiffOp is a single line of a line-based diff
+type DiffOp struct {
+	Op   string `json:"op"` // "equal", "insert" or "delete"
+	Text string `json:"text"`
+}
+
+// maxDiffCells bounds the LCS table of the changed lines (8 bytes per cell). Above it
+// the changed lines are shown as replaced as a whole.
+const maxDiffCells = 1 << 20
+
+// DiffLines computes a line-based diff between two sequences using the
+// longest common subsequence. Deletions are emitted before insertions
+// when lines are replaced. Lines shared at the start and end are matched
+// first; when the lines in between are too many to compare, they are
+// reported as deleted and inserted without looking for common lines.
+func DiffLines(before, after []string) []DiffOp {
+	ops := []DiffOp{}
+
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		ops = append(ops, DiffOp{Op: "equal", Text: before[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	middleBefore := before[prefix : len(before)-suffix]
+	middleAfter := after[prefix : len(after)-suffix]
+	if len(middleBefore)*len(middleAfter) > maxDiffCells {
+		ops = appendReplaced(ops, middleBefore, middleAfter)
+	} else {
+		ops = appendLCSDiff(ops, middleBefore, middleAfter)
+	}
+
+	for _, line := range before[len(before)-suffix:] {
+		ops = append(ops, DiffOp{Op: "equal", Text: line})
+	}
+	return ops
+}
+
+// appendLCSDiff appends the diff of two sequences based on their longest common subsequence
+func appendLCSDiff(ops []DiffOp, before, after []string) []DiffOp {
+	n, m := len(before), len(after)
+
+	// lcs[i][j] holds the LCS length of before[i:] and after[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case before[i] == after[j]:
+			ops = append(ops, DiffOp{Op: "equal", Text: before[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, DiffOp{Op: "delete", Text: before[i]})
+			i++
+		default:
+			ops = append(ops, DiffOp{Op: "insert", Text: after[j]})
+			j++
+		}
+	}
+	return appendReplaced(ops, before[i:], after[j:])
+}
+
+// appendReplaced appends all of before as deleted and all of after as inserted
+func appendReplaced(ops []DiffOp, before, after []string) []DiffOp {
+	for _, line := range before {
+		ops = append(ops, DiffOp{Op: "delete", Text: line})
+	}
+	for _, line := range after {
+		ops = append(ops, DiffOp{Op: "insert", Text: line})
+	}
+	return ops
+}
@@ -1,103 +0,0 @@
-package utils
-
-import (
-	"crypto/tls"
-	"net/smtp"
-	"strings"
-)
-
-// SendMailWithTLS sends email using STARTTLS
-func SendMailWithTLS(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
-	client, err := smtp.Dial(addr)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
-
-	// Start TLS
-	if err = client.StartTLS(&tls.Config{ServerName: strings.Split(addr, ":")[0]}); err != nil {
-		return err
-	}
-
-	// Authenticate
-	if auth != nil {
-		if err = client.Auth(auth); err != nil {
-			return err
-		}
-	}
-
-	// Send email
-	if err = client.Mail(from); err != nil {
-		return err
-	}
-
-	for _, recipient := range to {
-		if err = client.Rcpt(recipient); err != nil {
-			return err
-		}
-	}
-
-	writer, err := client.Data()
-	if err != nil {
-		return err
-	}
-
-	_, err = writer.Write(msg)
-	if err != nil {
-		return err
-	}
-
-	return writer.Close()
-}
-
-// SendMailWithSSL sends email using SSL/TLS
-func SendMailWithSSL(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
-	host := strings.Split(addr, ":")[0]
-
-	// Create TLS connection
-	tlsConfig := &tls.Config{
-		ServerName: host,
-	}
-
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
-
-	client, err := smtp.NewClient(conn, host)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
-
-	// Authenticate
-	if auth != nil {
-		if err = client.Auth(auth); err != nil {
-			return err
-		}
-	}
-
-	// Send email
-	if err = client.Mail(from); err != nil {
-		return err
-	}
-
-	for _, recipient := range to {
-		if err = client.Rcpt(recipient); err != nil {
-			return err
-		}
-	}
-
-	writer, err := client.Data()
-	if err != nil {
-		return err
-	}
-
-	_, err = writer.Write(msg)
-	if err != nil {
-		return err
-	}
-
-	return writer.Close()
-}
@@ -47,6 +47,7 @@ export interface Event {
   date: string;
   votes: number;
   content: string; // Markdown content
+  content_html?: string; // Content rendered and sanitized by the server
   order: number; // Order for sorting within status
   created_at: string;
   updated_at: string;
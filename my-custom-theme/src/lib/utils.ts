@@ -129,26 +129,6 @@ export function groupEventsByStatus(events: ParsedEvent[]) {
   };
 }
 
-/**
- * Convert markdown to HTML (basic implementation)
- */
-export function markdownToHtml(markdown: string): string {
-  return markdown
-    .replace(/^### (.*$)/gm, '<h3 class="text-base font-semibold mb-2">$1</h3>')
-    .replace(/^## (.*$)/gm, '<h2 class="text-lg font-semibold mb-3">$1</h2>')
-    .replace(/^# (.*$)/gm, '<h1 class="text-xl font-semibold mb-4">$1</h1>')
-    .replace(/\*\*(.*?)\*\*/g, "<strong>$1</strong>")
-    .replace(/\*(.*?)\*/g, "<em>$1</em>")
-    .replace(
-      /`(.*?)`/g,
-      '<code class="bg-muted px-1 py-0.5 rounded text-sm">$1</code>',
-    )
-    .replace(/\n\n/g, '</p><p class="mb-4">')
-    .replace(/\n/g, "<br>")
-    .replace(/^/, '<p class="mb-4">')
-    .replace(/$/, "</p>");
-}
-
 /**
  * Debounce function for search inputs
  */
@@ -30,7 +30,7 @@ fi
 if [ ! -f "backend/main" ]; then
     echo -e "${YELLOW}🔨 Building backend...${NC}"
     cd backend
-    go build -o main .
+    go build -tags sqlite_fts5 -o main .
     cd ..
 fi
 
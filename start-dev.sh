@@ -63,7 +63,7 @@ if [ ! -f "backend/main" ] || [ "$REBUILD" = true ]; then
         echo -e "${YELLOW}⚠️  Backend binary not found. Building...${NC}"
     fi
     cd backend
-    go build -o main .
+    go build -tags sqlite_fts5 -o main .
     cd ..
     echo -e "${GREEN}✅ Backend built successfully${NC}"
 fi